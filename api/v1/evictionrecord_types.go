@@ -0,0 +1,42 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PDBWatcherLabel is set on EvictionRecords to the name of the PDBWatcher they belong to
+const PDBWatcherLabel = "pdb-autoscaler/pdbwatcher"
+
+// EvictionRecordSpec defines an eviction intercepted by the webhook
+type EvictionRecordSpec struct {
+	PDBWatcherName string `json:"pdbWatcherName"` // PDBWatcher the eviction was attributed to
+	EvictionLog    `json:",inline"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="PDBWatcher",type=string,JSONPath=`.spec.pdbWatcherName`
+// +kubebuilder:printcolumn:name="Pod",type=string,JSONPath=`.spec.podName`
+// +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.spec.nodeName`
+// +kubebuilder:printcolumn:name="Requester",type=string,JSONPath=`.spec.requester`
+// +kubebuilder:printcolumn:name="Evicted",type=date,JSONPath=`.spec.evictionTime`
+
+// EvictionRecord is the Schema for the evictionrecords API
+type EvictionRecord struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec EvictionRecordSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EvictionRecordList contains a list of EvictionRecord
+type EvictionRecordList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EvictionRecord `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EvictionRecord{}, &EvictionRecordList{})
+}
@@ -1,26 +1,258 @@
 package v1
 
 import (
+	"time"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// Annotations used to opt a Deployment or StatefulSet into autoscaling without
+// authoring a PDBWatcher. The controller manages an implicit PDBWatcher for
+// every workload carrying EnabledAnnotation set to "true".
+const (
+	EnabledAnnotation        = "pdb-autoscaler/enabled"
+	MaxSurgeAnnotation       = "pdb-autoscaler/max-surge"       // Integer or percentage, e.g. "2" or "25%"
+	EvictionWindowAnnotation = "pdb-autoscaler/eviction-window" // Go duration, e.g. "5m"
+	EvictionModeAnnotation   = "pdb-autoscaler/eviction-mode"   // Allow or SurgeFirst
+
+	// ManagedByLabel marks PDBWatchers created from workload annotations
+	ManagedByLabel = "pdb-autoscaler/managed-by"
+)
+
+// DefaultEvictionWindow is how long an eviction attempt counts towards a scale up when
+// the PDBWatcher sets no EvictionWindow
+const DefaultEvictionWindow = 5 * time.Minute
+
+// SurgeDisabledAnnotation set to "true" on a PDB marks its DisruptionsAllowed of 0 as
+// intended, so evictions it blocks are never answered with a surge
+const SurgeDisabledAnnotation = "pdb-autoscaler/surge-disabled"
+
+// ConditionSurgeEnabled is False with one of the SurgeDisabledReasons when the PDBWatcher
+// refuses to surge, because the PDB or workload blocks evictions by design
+const ConditionSurgeEnabled = "SurgeEnabled"
+
+// Reasons of the SurgeEnabled condition
+const (
+	SurgeEnabledReason          = "Enabled"
+	SurgeDisabledByAnnotation   = "DisabledByAnnotation" // The PDB carries SurgeDisabledAnnotation
+	SurgeDisabledMaxUnavailable = "MaxUnavailableZero"   // The PDB allows no pod to be unavailable
+	SurgeDisabledMaxSurge       = "MaxSurgeZero"         // The workload may not run extra pods
+)
+
+// ConditionDegraded is True with reason PodsFailing when the PDB blocks evictions because
+// pods of the workload are failing, which surged pods of the same template would inherit
+const ConditionDegraded = "Degraded"
+
+// Reasons of the Degraded condition
+const (
+	DegradedPodsFailing = "PodsFailing"
+	DegradedPodsHealthy = "PodsHealthy"
+)
+
+// ConditionOverlappingPDBs is True with reason MultiplePDBs when pods of the workload are
+// selected by more than one PDB, the eviction API refuses to evict them
+const ConditionOverlappingPDBs = "OverlappingPDBs"
+
+// Reasons of the OverlappingPDBs condition
+const (
+	OverlappingPDBsMultiple = "MultiplePDBs"
+	OverlappingPDBsNone     = "SinglePDB"
+)
+
+// DeletionCostManagedAnnotation marks the pods whose pod-deletion-cost was set by the
+// controller, so it only cleans up its own
+const DeletionCostManagedAnnotation = "pdb-autoscaler/deletion-cost-managed"
+
+// Kinds of workloads a PDBWatcher can scale
+const (
+	DeploymentKind  = "Deployment"
+	StatefulSetKind = "StatefulSet"
+)
+
+// EvictionMode is how the webhook answers evictions of pods covered by a PDBWatcher
+type EvictionMode string
+
+const (
+	// EvictionModeAllow lets every eviction through, the PDB rejects the ones it blocks
+	EvictionModeAllow EvictionMode = "Allow"
+	// EvictionModeSurgeFirst denies evictions while the PDB allows no disruptions,
+	// asking the client to retry once the surge is Ready
+	EvictionModeSurgeFirst EvictionMode = "SurgeFirst"
+)
+
+// RevertCondition is when a surge is reverted
+type RevertCondition string
+
+const (
+	// RevertWhenDisruptionsAllowed reverts once the PDB allows disruptions again
+	RevertWhenDisruptionsAllowed RevertCondition = "DisruptionsAllowed"
+	// RevertWhenDrainComplete also waits until no pod of the workload is left on a
+	// cordoned node and no eviction was requested within the eviction window
+	RevertWhenDrainComplete RevertCondition = "DrainComplete"
+)
+
+// PodDeletionCostPolicy is how pods are ranked for removal when a surge of a Deployment is reverted
+type PodDeletionCostPolicy string
+
+const (
+	// PodDeletionCostNone leaves the choice to the ReplicaSet controller
+	PodDeletionCostNone PodDeletionCostPolicy = "None"
+	// PodDeletionCostCordonedFirst removes the pods on cordoned or NoExecute tainted nodes first
+	PodDeletionCostCordonedFirst PodDeletionCostPolicy = "CordonedFirst"
+	// PodDeletionCostCordonedThenOldest removes the pods on cordoned nodes first, then the oldest
+	PodDeletionCostCordonedThenOldest PodDeletionCostPolicy = "CordonedThenOldest"
 )
 
-// EvictionLog defines a log entry for pod evictions
+// SurgeAggregation is how the surges requested by PDBWatchers sharing a workload are combined
+type SurgeAggregation string
+
+const (
+	// SurgeAggregationMax surges by the largest request, for PDBs covering the same pods
+	SurgeAggregationMax SurgeAggregation = "Max"
+	// SurgeAggregationSum surges by the sum of the requests, for PDBs covering disjoint pods, e.g. one per zone
+	SurgeAggregationSum SurgeAggregation = "Sum"
+)
+
+// EvictionCountBy is how evictions within the window are counted towards a surge
+type EvictionCountBy string
+
+const (
+	// CountByAttempts counts every eviction attempt, including retries of the same pod
+	CountByAttempts EvictionCountBy = "Attempts"
+	// CountByDistinctPods counts each evicted pod once, however often it was retried
+	CountByDistinctPods EvictionCountBy = "DistinctPods"
+)
+
+// TriggerPolicy decides when the evictions blocked within the window warrant a surge
+type TriggerPolicy struct {
+	// MinEvictions is the number of evictions within the window needed to surge, 1 if unset
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MinEvictions int32 `json:"minEvictions,omitempty"`
+
+	// CountBy is Attempts if unset
+	// +kubebuilder:validation:Enum=Attempts;DistinctPods
+	// +optional
+	CountBy EvictionCountBy `json:"countBy,omitempty"`
+}
+
+// StabilizationPolicy delays reverts and consecutive surges, so a workload isn't
+// reverted and surged again between the pods of a multi-pod drain
+type StabilizationPolicy struct {
+	// MinSurgeDuration is how long a surge is held at least before it is reverted, 0 if unset
+	// +optional
+	MinSurgeDuration *metav1.Duration `json:"minSurgeDuration,omitempty"`
+
+	// Cooldown is how long after a revert no new surge is started, 0 if unset
+	// +optional
+	Cooldown *metav1.Duration `json:"cooldown,omitempty"`
+
+	// DisruptionsAllowedWindow is how long the PDB must keep allowing disruptions
+	// before a surge is reverted, 0 if unset
+	// +optional
+	DisruptionsAllowedWindow *metav1.Duration `json:"disruptionsAllowedWindow,omitempty"`
+}
+
+// ScaleDownPolicy reverts a surge stepwise instead of in one update
+type ScaleDownPolicy struct {
+	// Step is the number of replicas removed at a time, 1 if unset. A step never removes
+	// more replicas than the PDB allows to be disrupted, and the next step waits until the
+	// PDB observed the previous one and is healthy again.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Step int32 `json:"step,omitempty"`
+}
+
+// EvictionLog defines a log entry for pod evictions, recorded as an EvictionRecord
 type EvictionLog struct {
-	PodName      string `json:"podName"`
-	EvictionTime string `json:"evictionTime"`
+	PodName            string      `json:"podName"`
+	PodUID             types.UID   `json:"podUID,omitempty"`
+	NodeName           string      `json:"nodeName,omitempty"`  // Node the pod was running on
+	Requester          string      `json:"requester,omitempty"` // User or serviceaccount that requested the eviction
+	DisruptionsAllowed int32       `json:"disruptionsAllowed"`  // PDB's DisruptionsAllowed when the eviction was requested
+	EvictionTime       metav1.Time `json:"evictionTime"`        // Time of the latest attempt
+	Attempts           int32       `json:"attempts,omitempty"`  // Attempts coalesced into this entry, 1 if unset
 }
 
 // PDBWatcherSpec defines the desired state of PDBWatcher
 type PDBWatcherSpec struct {
 	PDBName        string `json:"pdbName"`
-	DeploymentName string `json:"deploymentName"`
+	DeploymentName string `json:"deploymentName"` // Name of the workload to scale, of kind TargetKind
+
+	// TargetKind is the kind of workload named by DeploymentName, Deployment if unset
+	// +kubebuilder:validation:Enum=Deployment;StatefulSet
+	// +optional
+	TargetKind string `json:"targetKind,omitempty"`
+
+	// MaxSurge overrides the number of replicas added when evictions are blocked.
+	// Defaults to the Deployment's rolling update maxSurge, or 1.
+	// +optional
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+
+	// EvictionWindow is how long an eviction attempt counts towards a scale up, 5m if unset
+	// +optional
+	EvictionWindow *metav1.Duration `json:"evictionWindow,omitempty"`
+
+	// Trigger decides when evictions warrant a surge, any eviction within the window if unset
+	// +optional
+	Trigger *TriggerPolicy `json:"trigger,omitempty"`
+
+	// Stabilization delays reverts and consecutive surges, a surge is reverted as soon
+	// as the PDB allows disruptions if unset
+	// +optional
+	Stabilization *StabilizationPolicy `json:"stabilization,omitempty"`
+
+	// RevertWhen is DisruptionsAllowed if unset
+	// +kubebuilder:validation:Enum=DisruptionsAllowed;DrainComplete
+	// +optional
+	RevertWhen RevertCondition `json:"revertWhen,omitempty"`
+
+	// ScaleDown reverts a surge stepwise, aborting when a new eviction arrives. A surge
+	// is reverted in one update if unset.
+	// +optional
+	ScaleDown *ScaleDownPolicy `json:"scaleDown,omitempty"`
+
+	// PodDeletionCost sets controller.kubernetes.io/pod-deletion-cost on the pods of a
+	// Deployment before a surge is reverted, so the right pods are removed. None if unset.
+	// +kubebuilder:validation:Enum=None;CordonedFirst;CordonedThenOldest
+	// +optional
+	PodDeletionCost PodDeletionCostPolicy `json:"podDeletionCost,omitempty"`
+
+	// SurgeAggregation combines the surges requested by PDBWatchers targeting the same
+	// workload, the baseline owner's applies. Max if unset.
+	// +kubebuilder:validation:Enum=Max;Sum
+	// +optional
+	SurgeAggregation SurgeAggregation `json:"surgeAggregation,omitempty"`
+
+	// EvictionMode is Allow if unset. With SurgeFirst, the webhook denies evictions
+	// while the PDB allows no disruptions, with a hint to retry once the surge is Ready.
+	// +kubebuilder:validation:Enum=Allow;SurgeFirst
+	// +optional
+	EvictionMode EvictionMode `json:"evictionMode,omitempty"`
 }
 
 // PDBWatcherStatus defines the observed state of PDBWatcher
 type PDBWatcherStatus struct {
-	EvictionLogs    []EvictionLog `json:"evictionLogs,omitempty"`
-	MinReplicas     int32         `json:"minReplicas"`     // Minimum number of replicas to maintain
-	ResourceVersion string        `json:"resourceVersion"` // Resource version of the deployment
+	EvictionCount           int32        `json:"evictionCount,omitempty"`           // Evictions within the eviction window, counted by the trigger's CountBy
+	LastEvictionTime        *metav1.Time `json:"lastEvictionTime,omitempty"`        // Time of the most recent eviction
+	MinReplicas             int32        `json:"minReplicas"`                       // Minimum number of replicas to maintain
+	ResourceVersion         string       `json:"resourceVersion"`                   // Resource version of the deployment
+	SurgeStartTime          *metav1.Time `json:"surgeStartTime,omitempty"`          // When the current surge, or surge requested from the baseline owner, started. Unset when not surging
+	DisruptionsAllowedSince *metav1.Time `json:"disruptionsAllowedSince,omitempty"` // Since when the PDB allows disruptions, unset while it allows none
+	LastRevertTime          *metav1.Time `json:"lastRevertTime,omitempty"`          // When the last surge was reverted, the cooldown starts from it
+	ScaleDownStartTime      *metav1.Time `json:"scaleDownStartTime,omitempty"`      // When the current stepwise scale-down started, unset when not scaling down
+	Workload                string       `json:"workload,omitempty"`                // Workload scaled for the PDB, as Kind/name
+	BaselineOwner           string       `json:"baselineOwner,omitempty"`           // PDBWatcher owning the baseline of a workload shared with other PDBWatchers
+	DesiredSurge            int32        `json:"desiredSurge,omitempty"`            // Replicas the PDB needs above the baseline, 0 when it needs no surge
+	ScaledReplicas          int32        `json:"scaledReplicas,omitempty"`          // Replicas the baseline owner last set or adopted, so another PDBWatcher can take its baseline over
+
+	// Conditions of the PDBWatcher, SurgeEnabled, Degraded and OverlappingPDBs
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -21,12 +21,15 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EvictionLog) DeepCopyInto(out *EvictionLog) {
 	*out = *in
+	in.EvictionTime.DeepCopyInto(&out.EvictionTime)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvictionLog.
@@ -39,12 +42,86 @@ func (in *EvictionLog) DeepCopy() *EvictionLog {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EvictionRecord) DeepCopyInto(out *EvictionRecord) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvictionRecord.
+func (in *EvictionRecord) DeepCopy() *EvictionRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(EvictionRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EvictionRecord) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EvictionRecordList) DeepCopyInto(out *EvictionRecordList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]EvictionRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvictionRecordList.
+func (in *EvictionRecordList) DeepCopy() *EvictionRecordList {
+	if in == nil {
+		return nil
+	}
+	out := new(EvictionRecordList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EvictionRecordList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EvictionRecordSpec) DeepCopyInto(out *EvictionRecordSpec) {
+	*out = *in
+	in.EvictionLog.DeepCopyInto(&out.EvictionLog)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvictionRecordSpec.
+func (in *EvictionRecordSpec) DeepCopy() *EvictionRecordSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EvictionRecordSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PDBWatcher) DeepCopyInto(out *PDBWatcher) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -101,6 +178,31 @@ func (in *PDBWatcherList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PDBWatcherSpec) DeepCopyInto(out *PDBWatcherSpec) {
 	*out = *in
+	if in.MaxSurge != nil {
+		in, out := &in.MaxSurge, &out.MaxSurge
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.EvictionWindow != nil {
+		in, out := &in.EvictionWindow, &out.EvictionWindow
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Trigger != nil {
+		in, out := &in.Trigger, &out.Trigger
+		*out = new(TriggerPolicy)
+		**out = **in
+	}
+	if in.Stabilization != nil {
+		in, out := &in.Stabilization, &out.Stabilization
+		*out = new(StabilizationPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ScaleDown != nil {
+		in, out := &in.ScaleDown, &out.ScaleDown
+		*out = new(ScaleDownPolicy)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PDBWatcherSpec.
@@ -116,10 +218,32 @@ func (in *PDBWatcherSpec) DeepCopy() *PDBWatcherSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PDBWatcherStatus) DeepCopyInto(out *PDBWatcherStatus) {
 	*out = *in
-	if in.EvictionLogs != nil {
-		in, out := &in.EvictionLogs, &out.EvictionLogs
-		*out = make([]EvictionLog, len(*in))
-		copy(*out, *in)
+	if in.LastEvictionTime != nil {
+		in, out := &in.LastEvictionTime, &out.LastEvictionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.SurgeStartTime != nil {
+		in, out := &in.SurgeStartTime, &out.SurgeStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.DisruptionsAllowedSince != nil {
+		in, out := &in.DisruptionsAllowedSince, &out.DisruptionsAllowedSince
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRevertTime != nil {
+		in, out := &in.LastRevertTime, &out.LastRevertTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ScaleDownStartTime != nil {
+		in, out := &in.ScaleDownStartTime, &out.ScaleDownStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 }
 
@@ -132,3 +256,63 @@ func (in *PDBWatcherStatus) DeepCopy() *PDBWatcherStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleDownPolicy) DeepCopyInto(out *ScaleDownPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleDownPolicy.
+func (in *ScaleDownPolicy) DeepCopy() *ScaleDownPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleDownPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StabilizationPolicy) DeepCopyInto(out *StabilizationPolicy) {
+	*out = *in
+	if in.MinSurgeDuration != nil {
+		in, out := &in.MinSurgeDuration, &out.MinSurgeDuration
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Cooldown != nil {
+		in, out := &in.Cooldown, &out.Cooldown
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.DisruptionsAllowedWindow != nil {
+		in, out := &in.DisruptionsAllowedWindow, &out.DisruptionsAllowedWindow
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StabilizationPolicy.
+func (in *StabilizationPolicy) DeepCopy() *StabilizationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(StabilizationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TriggerPolicy) DeepCopyInto(out *TriggerPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TriggerPolicy.
+func (in *TriggerPolicy) DeepCopy() *TriggerPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(TriggerPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -17,25 +17,37 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	appsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+	"github.com/Javier090/k8s-pdb-autoscaler/internal/certs"
 	controllers "github.com/Javier090/k8s-pdb-autoscaler/internal/controller"
+	"github.com/Javier090/k8s-pdb-autoscaler/internal/tracing"
+	evictionwebhook "github.com/Javier090/k8s-pdb-autoscaler/internal/webhook"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -57,6 +69,21 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var evictionRecordTTL time.Duration
+	var enableControllers bool
+	var enableEvictionWebhook bool
+	var webhookPort int
+	var webhookCertDir string
+	var evictionWebhookPath string
+	var tlsMinVersion string
+	var tlsCipherSuites string
+	var manageWebhookCerts bool
+	var webhookNamespace string
+	var webhookCertSecret string
+	var webhookService string
+	var webhookConfiguration string
+	var tracingEndpoint string
+	var tracingInsecure bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metric endpoint binds to. "+
 		"Use the port :8080. If not set, it will be 0 in order to disable the metrics server")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -67,14 +94,59 @@ func main() {
 		"If set the metrics endpoint is served securely")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.DurationVar(&evictionRecordTTL, "eviction-record-ttl", time.Hour,
+		"How long EvictionRecords are kept after their latest eviction before being deleted. "+
+			"Records are never deleted before their PDBWatcher's eviction window has passed.")
+	flag.BoolVar(&enableControllers, "enable-controllers", true,
+		"If set the PDBWatcher, workload and EvictionRecord controllers are run")
+	flag.BoolVar(&enableEvictionWebhook, "enable-eviction-webhook", false,
+		"If set the eviction webhook is served on the webhook server")
+	flag.IntVar(&webhookPort, "webhook-port", webhook.DefaultPort, "The port the webhook server serves at.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir",
+		filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs"),
+		"The directory containing the webhook server's tls.crt and tls.key")
+	flag.StringVar(&evictionWebhookPath, "eviction-webhook-path", evictionwebhook.EvictionPath,
+		"The path the eviction webhook is served on, it must match the ValidatingWebhookConfiguration")
+	flag.StringVar(&tlsMinVersion, "tls-min-version", "VersionTLS12",
+		"The minimum TLS version of the metrics and webhook servers. "+
+			"One of VersionTLS10, VersionTLS11, VersionTLS12 or VersionTLS13")
+	flag.StringVar(&tlsCipherSuites, "tls-cipher-suites", "",
+		"Comma-separated list of TLS cipher suites for the metrics and webhook servers, "+
+			"e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256. If not set, Go's defaults are used. "+
+			"Cipher suites are not configurable for TLS 1.3")
+	flag.BoolVar(&manageWebhookCerts, "manage-webhook-certs", false,
+		"If set the webhook generates its own CA and serving certificate, stores them in "+
+			"--webhook-cert-secret, writes them to --webhook-cert-dir, patches the caBundle of "+
+			"--webhook-configuration and renews them before they expire")
+	flag.StringVar(&webhookNamespace, "webhook-namespace", "",
+		"The namespace of the webhook Service and certificate Secret. Defaults to the pod's namespace")
+	flag.StringVar(&webhookCertSecret, "webhook-cert-secret", "eviction-webhook-certs",
+		"The Secret the managed webhook certificate is stored in")
+	flag.StringVar(&webhookService, "webhook-service", "eviction-webhook-service",
+		"The Service in front of the webhook, the managed certificate is issued for its DNS names")
+	flag.StringVar(&webhookConfiguration, "webhook-configuration", "eviction-webhook",
+		"The ValidatingWebhookConfiguration whose caBundle is patched with the managed CA")
+	flag.StringVar(&tracingEndpoint, "tracing-endpoint", "",
+		"The OTLP/gRPC endpoint spans are exported to, e.g. localhost:4317 for a local collector. "+
+			"If not set, tracing is disabled")
+	flag.BoolVar(&tracingInsecure, "tracing-insecure", false,
+		"If set spans are exported without TLS")
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
+	envErr := setFlagsFromEnv("webhook-port", "webhook-cert-dir", "eviction-webhook-path",
+		"tls-min-version", "tls-cipher-suites", "manage-webhook-certs", "webhook-namespace",
+		"webhook-cert-secret", "webhook-service", "webhook-configuration", "tracing-endpoint", "tracing-insecure")
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if envErr != nil {
+		setupLog.Error(envErr, "unable to read flags from the environment")
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -91,11 +163,29 @@ func main() {
 		tlsOpts = append(tlsOpts, disableHTTP2)
 	}
 
+	minVersion, ok := tlsVersions[tlsMinVersion]
+	if !ok {
+		setupLog.Error(fmt.Errorf("unknown TLS version %q", tlsMinVersion), "invalid --tls-min-version")
+		os.Exit(1)
+	}
+	cipherSuites, err := parseCipherSuites(tlsCipherSuites)
+	if err != nil {
+		setupLog.Error(err, "invalid --tls-cipher-suites")
+		os.Exit(1)
+	}
+	tlsOpts = append(tlsOpts, func(c *tls.Config) {
+		c.MinVersion = minVersion
+		c.CipherSuites = cipherSuites
+	})
+
 	webhookServer := webhook.NewServer(webhook.Options{
+		Port:    webhookPort,
+		CertDir: webhookCertDir,
 		TLSOpts: tlsOpts,
 	})
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	restConfig := ctrl.GetConfigOrDie()
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
 			BindAddress:   metricsAddr,
@@ -123,12 +213,96 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = (&controllers.PDBWatcherReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "PDBWatcher")
-		os.Exit(1)
+	ctx := ctrl.SetupSignalHandler()
+
+	if tracingEndpoint != "" {
+		serviceName := "pdb-autoscaler-controller"
+		if !enableControllers {
+			serviceName = "pdb-autoscaler-webhook"
+		}
+		shutdownTracing, err := tracing.Setup(ctx, serviceName, tracingEndpoint, tracingInsecure)
+		if err != nil {
+			setupLog.Error(err, "unable to set up tracing")
+			os.Exit(1)
+		}
+		defer func() {
+			// Flush the remaining spans, the signal handler's context is already cancelled
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(shutdownCtx); err != nil {
+				setupLog.Error(err, "unable to flush spans")
+			}
+		}()
+	}
+
+	if enableControllers {
+		// The manager's recorder only speaks core/v1 Events, scaling decisions are
+		// recorded with events.k8s.io/v1 to reference the PDBWatcher and workload together
+		eventBroadcaster := events.NewBroadcaster(&events.EventSinkImpl{Interface: kubernetes.NewForConfigOrDie(restConfig).EventsV1()})
+		if err = eventBroadcaster.StartRecordingToSinkWithContext(ctx); err != nil {
+			setupLog.Error(err, "unable to start event broadcaster")
+			os.Exit(1)
+		}
+
+		if err = (&controllers.PDBWatcherReconciler{
+			Client:   mgr.GetClient(),
+			Scheme:   mgr.GetScheme(),
+			Recorder: eventBroadcaster.NewRecorder(mgr.GetScheme(), "pdb-autoscaler"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "PDBWatcher")
+			os.Exit(1)
+		}
+		for _, kind := range []string{appsv1.DeploymentKind, appsv1.StatefulSetKind} {
+			if err = (&controllers.WorkloadReconciler{
+				Client: mgr.GetClient(),
+				Scheme: mgr.GetScheme(),
+				Kind:   kind,
+			}).SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", kind)
+				os.Exit(1)
+			}
+		}
+		if err = (&controllers.EvictionRecordReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			TTL:    evictionRecordTTL,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "EvictionRecord")
+			os.Exit(1)
+		}
+	}
+	if enableEvictionWebhook {
+		if err = evictionwebhook.SetupEvictionWebhookWithManager(ctx, mgr, evictionWebhookPath); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Eviction")
+			os.Exit(1)
+		}
+	}
+	if enableEvictionWebhook && manageWebhookCerts {
+		// The manager's client is not usable before its cache starts, and the
+		// certificate must be in place before the webhook server starts
+		certClient, err := client.New(restConfig, client.Options{Scheme: scheme})
+		if err != nil {
+			setupLog.Error(err, "unable to create certificate client")
+			os.Exit(1)
+		}
+		if webhookNamespace == "" {
+			webhookNamespace = podNamespace()
+		}
+		rotator := &certs.Rotator{
+			Client:                   certClient,
+			SecretKey:                types.NamespacedName{Namespace: webhookNamespace, Name: webhookCertSecret},
+			ServiceName:              webhookService,
+			WebhookConfigurationName: webhookConfiguration,
+			CertDir:                  webhookCertDir,
+		}
+		if err := rotator.Bootstrap(ctx); err != nil {
+			setupLog.Error(err, "unable to bootstrap webhook certificate")
+			os.Exit(1)
+		}
+		if err := mgr.Add(rotator); err != nil {
+			setupLog.Error(err, "unable to add certificate rotator to manager")
+			os.Exit(1)
+		}
 	}
 	// +kubebuilder:scaffold:builder
 
@@ -140,10 +314,76 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if enableEvictionWebhook {
+		// Not ready until the webhook server is serving
+		if err := mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+			setupLog.Error(err, "unable to set up webhook ready check")
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// tlsVersions are the accepted values of --tls-min-version
+var tlsVersions = map[string]uint16{
+	"VersionTLS10": tls.VersionTLS10,
+	"VersionTLS11": tls.VersionTLS11,
+	"VersionTLS12": tls.VersionTLS12,
+	"VersionTLS13": tls.VersionTLS13,
+}
+
+// parseCipherSuites parses a comma-separated list of cipher suite names, only
+// suites considered secure by crypto/tls are accepted
+func parseCipherSuites(names string) ([]uint16, error) {
+	if names == "" {
+		return nil, nil
+	}
+
+	ids := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		ids[suite.Name] = suite.ID
+	}
+
+	var suites []uint16
+	for _, name := range strings.Split(names, ",") {
+		id, ok := ids[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
+
+// setFlagsFromEnv sets the named flags that were not passed on the command line
+// from their environment variable, e.g. WEBHOOK_CERT_DIR for --webhook-cert-dir
+func setFlagsFromEnv(names ...string) error {
+	passed := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { passed[f.Name] = true })
+
+	for _, name := range names {
+		env := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		value, ok := os.LookupEnv(env)
+		if !ok || passed[name] {
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", env, err)
+		}
+	}
+	return nil
+}
+
+// podNamespace returns the namespace the manager runs in, or default outside a cluster
+func podNamespace() string {
+	namespace, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	if err != nil {
+		return "default"
+	}
+	return strings.TrimSpace(string(namespace))
+}
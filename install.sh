@@ -5,27 +5,19 @@ CONTROLLER_IMAGE="javgarcia0907/k8s-pdb-autoscaler:webhookv1"
 WEBHOOK_IMAGE="javgarcia0907/k8s-pdb-autoscaler:webhookv1"
 NAMESPACE="default"
 DEPLOYMENT_FILE="config/manager/manager.yaml"
-SERVICE_ACCOUNT_FILE="service_account.yaml"
-ROLE_FILE="role.yaml"
-ROLE_BINDING_FILE="role_binding.yaml"
-CLUSTER_ROLE_FILE="clusterrole.yaml"
-CLUSTER_ROLE_BINDING_FILE="clusterrolebinding.yaml"
+SERVICE_ACCOUNT_FILE="config/manager/manager_service.yaml"
+CRD_DIR="config/crd/bases"
+CLUSTER_ROLE_FILE="config/rbac/managerrole.yaml"
+CLUSTER_ROLE_BINDING_FILE="config/rbac/managerrole_bind.yaml"
+LEADER_ELECTION_ROLE_FILE="config/rbac/leader_election_role.yaml"
+LEADER_ELECTION_ROLE_BINDING_FILE="config/rbac/leader_election_role_binding.yaml"
 WEBHOOK_CLUSTER_ROLE_FILE="config/webhook/manifests/Roles/webhookclusterrole.yaml"
+WEBHOOK_ROLE_FILE="config/webhook/manifests/Roles/webhookrole.yaml"
 WEBHOOK_ROLE_BINDING_FILE="config/webhook/manifests/Roles/webhookrolebind.yaml"
 WEBHOOK_SERVICE_FILE="config/webhook/manifests/web_service.yml"
 WEBHOOK_CONFIGURATION_FILE="config/webhook/manifests/webhook_configuration.yaml"
 WEBHOOK_DEPLOYMENT_FILE="config/webhook/manifests/webhook_deployment.yaml"
-WEBHOOK_CERTS_SECRET="webhook-certs"
-WEBHOOK_CERT_FILE="config/webhook/manifests/tls.crt"
-WEBHOOK_KEY_FILE="config/webhook/manifests/tls.key"
-WEBHOOK_CSR_FILE="config/webhook/manifests/webhook.csr"
-CSR_CONF_FILE="config/webhook/manifests/csr.conf"
-CA_CERT_FILE="config/webhook/manifests/ca.crt"
-CA_KEY_FILE="config/webhook/manifests/ca.key"
 WEBHOOK_ACCOUNT_TOKEN="config/webhook/manifests/service-account-token-secret.yaml"
-PDBWATCHER_CRD_FILE="internal/controller/PDBRoles/pdbwatcher_crd.yaml"
-PDBWATCHER_ROLE="internal/controller/PDBRoles/clusterrole.yaml"
-PDBWATCHER_BIND="internal/controller/PDBRoles/clusterrolebinding.yaml"
 
 create_namespace() {
   kubectl get namespace $1 > /dev/null 2>&1
@@ -37,9 +29,9 @@ create_namespace() {
   fi
 }
 
-# Function to apply a yaml file
+# Function to apply a yaml file, or every yaml file of a directory
 apply_yaml() {
-  if [ -f $1 ]; then
+  if [ -e $1 ]; then
     echo "Applying $1"
     kubectl apply -f $1
   else
@@ -48,31 +40,6 @@ apply_yaml() {
   fi
 }
 
-# Function to create a secret
-create_secret() {
-  if [ -f $2 ] && [ -f $3 ]; then
-    echo "Creating secret $1"
-    kubectl create secret generic $1 --from-file=tls.crt=$2 --from-file=tls.key=$3 -n $NAMESPACE
-  else
-    echo "Secret files $2 or $3 do not exist"
-    exit 1
-  fi
-}
-
-# Generate certificates
-generate_certificates() {
-  echo "Generating certificates..."
-  openssl req -new -newkey rsa:2048 -nodes -keyout $WEBHOOK_KEY_FILE -out $WEBHOOK_CSR_FILE -config $CSR_CONF_FILE
-  openssl x509 -req -in $WEBHOOK_CSR_FILE -CA $CA_CERT_FILE -CAkey $CA_KEY_FILE -CAcreateserial -out $WEBHOOK_CERT_FILE -days 365 -extensions v3_req -extfile $CSR_CONF_FILE
-}
-
-# Auto inject CA Bundle to Webhook Config 
-inject_ca_bundle() {
-  echo "Injecting CA Bundle into webhook configuration..."
-  CA_BUNDLE=$(cat $CA_CERT_FILE | base64 | tr -d '\n')
-  sed -i "s/\${CA_BUNDLE}/${CA_BUNDLE}/g" $WEBHOOK_CONFIGURATION_FILE
-}
-
 # Start installation
 echo "Starting installation..."
 
@@ -95,23 +62,19 @@ docker push $WEBHOOK_IMAGE
 # Create namespace
 create_namespace $NAMESPACE
 
-# Generate certificates
-generate_certificates
+# The webhook generates its own certificates and patches the CA Bundle (--manage-webhook-certs)
 
-# Create Webhook Certificates Secret
-create_secret $WEBHOOK_CERTS_SECRET $WEBHOOK_CERT_FILE $WEBHOOK_KEY_FILE
-
-# Inject CA Bundle
-inject_ca_bundle
+# Apply the PDBWatcher and EvictionRecord CRDs
+apply_yaml $CRD_DIR
 
 # Apply Service Account
 apply_yaml $SERVICE_ACCOUNT_FILE
 
-# Apply Role
-apply_yaml $ROLE_FILE
+# Apply Leader Election Role
+apply_yaml $LEADER_ELECTION_ROLE_FILE
 
-# Apply Role Binding
-apply_yaml $ROLE_BINDING_FILE
+# Apply Leader Election Role Binding
+apply_yaml $LEADER_ELECTION_ROLE_BINDING_FILE
 
 # Apply Cluster Role
 apply_yaml $CLUSTER_ROLE_FILE
@@ -125,6 +88,9 @@ apply_yaml $DEPLOYMENT_FILE
 # Apply Webhook Cluster Role
 apply_yaml $WEBHOOK_CLUSTER_ROLE_FILE
 
+# Apply Webhook Role for the certificate Secret
+apply_yaml $WEBHOOK_ROLE_FILE
+
 # Apply Webhook Role Binding
 apply_yaml $WEBHOOK_ROLE_BINDING_FILE
 
@@ -140,13 +106,4 @@ apply_yaml $WEBHOOK_CONFIGURATION_FILE
 # Apply Webhook Deployment
 apply_yaml $WEBHOOK_DEPLOYMENT_FILE
 
-# Apply PDBWatcher CRD
-apply_yaml $PDBWATCHER_CRD_FILE
-
-# Apply PDBWatcher Role
-apply_yaml $PDBWATCHER_ROLE
-
-# Apply PDBWatcher Rolebind
-apply_yaml $PDBWATCHER_BIND
-
 echo "Installation completed."
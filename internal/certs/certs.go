@@ -0,0 +1,156 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// keyPair is a PEM encoded certificate and private key, along with the parsed certificate
+type keyPair struct {
+	certPEM []byte
+	keyPEM  []byte
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+}
+
+// newCA generates a self-signed CA valid from now for validity
+func newCA(name string, now time.Time, validity time.Duration) (*keyPair, error) {
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             now.Add(-time.Hour), // Tolerate clock skew
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	return newKeyPair(template, nil)
+}
+
+// newServingCert generates a serving certificate for dnsNames signed by ca
+func newServingCert(ca *keyPair, dnsNames []string, now time.Time, validity time.Duration) (*keyPair, error) {
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:    dnsNames,
+		NotBefore:   now.Add(-time.Hour), // Tolerate clock skew
+		NotAfter:    now.Add(validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	return newKeyPair(template, ca)
+}
+
+// newKeyPair generates a key and signs template with it, or with the CA if set
+func newKeyPair(template *x509.Certificate, ca *keyPair) (*keyPair, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate serial number: %w", err)
+	}
+	template.SerialNumber = serial
+
+	parent, signer := template, key
+	if ca != nil {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &keyPair{
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		cert:    cert,
+		key:     key,
+	}, nil
+}
+
+// parseKeyPair parses a PEM encoded certificate and key. If certPEM is a bundle,
+// the first certificate is the one matching the key.
+func parseKeyPair(certPEM, keyPEM []byte) (*keyPair, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, errors.New("no certificate found")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, errors.New("no private key found")
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if !key.PublicKey.Equal(cert.PublicKey) {
+		return nil, errors.New("private key does not match certificate")
+	}
+
+	return &keyPair{
+		certPEM: pem.EncodeToMemory(certBlock),
+		keyPEM:  keyPEM,
+		cert:    cert,
+		key:     key,
+	}, nil
+}
+
+// needsRenewal reports whether cert is past the renewal point of its lifetime
+func needsRenewal(cert *x509.Certificate, now time.Time) bool {
+	lifetime := cert.NotAfter.Sub(cert.NotBefore)
+	return now.After(cert.NotBefore.Add(lifetime * 2 / 3))
+}
+
+// validFor reports whether the serving cert is signed by ca and covers dnsNames
+func validFor(cert *x509.Certificate, ca *keyPair, dnsNames []string) bool {
+	if cert.CheckSignatureFrom(ca.cert) != nil {
+		return false
+	}
+	for _, name := range dnsNames {
+		if cert.VerifyHostname(name) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// caBundle returns the PEM bundle of ca followed by the certificates of previous
+// that are still valid, so certificates signed by a rotated-out CA keep being
+// trusted until every replica has picked up the new one
+func caBundle(ca *keyPair, previous []byte, now time.Time) []byte {
+	bundle := bytes.NewBuffer(append([]byte{}, ca.certPEM...))
+	for rest := previous; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || now.After(cert.NotAfter) || cert.Equal(ca.cert) {
+			continue
+		}
+		bundle.Write(pem.EncodeToMemory(block))
+	}
+	return bundle.Bytes()
+}
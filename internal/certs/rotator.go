@@ -0,0 +1,246 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Keys of the certificate Secret
+const (
+	caCertKey = "ca.crt" // Current CA, followed by rotated-out CAs that are still valid
+	caKeyKey  = "ca.key"
+	certKey   = corev1.TLSCertKey
+	keyKey    = corev1.TLSPrivateKeyKey
+)
+
+// +kubebuilder:rbac:groups=core,namespace=system,resources=secrets,resourceNames=eviction-webhook-certs,verbs=get;update
+// +kubebuilder:rbac:groups=core,namespace=system,resources=secrets,verbs=create
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations,resourceNames=eviction-webhook,verbs=get;patch
+
+// Rotator manages the webhook's serving certificate without cert-manager. The CA and
+// serving certificate are kept in a Secret shared by all replicas, the CA is patched into
+// the caBundle of the ValidatingWebhookConfiguration, and the serving certificate is then
+// written to CertDir where the webhook server hot-reloads it. Certificates are renewed once two thirds of
+// their lifetime has passed.
+type Rotator struct {
+	Client                   client.Client // Uncached, the Rotator is bootstrapped before the manager starts
+	SecretKey                types.NamespacedName
+	ServiceName              string // Service in front of the webhook, in the Secret's namespace
+	WebhookConfigurationName string
+	CertDir                  string
+	CAValidity               time.Duration // 10 years if unset
+	CertValidity             time.Duration // 1 year if unset
+	CheckInterval            time.Duration // 1h if unset
+}
+
+// Bootstrap makes sure a valid certificate is in CertDir, it must be called before the
+// webhook server starts
+func (r *Rotator) Bootstrap(ctx context.Context) error {
+	return r.rotate(ctx, time.Now())
+}
+
+// Start renews the certificates every CheckInterval until the context is cancelled
+func (r *Rotator) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("cert-rotator")
+
+	interval := r.CheckInterval
+	if interval == 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := r.rotate(ctx, time.Now()); err != nil {
+				logger.Error(err, "Failed to rotate webhook certificate")
+			}
+		}
+	}
+}
+
+// NeedLeaderElection returns false, every replica serves the webhook and needs the certificate
+func (r *Rotator) NeedLeaderElection() bool {
+	return false
+}
+
+// rotate renews the certificates in the Secret if needed, then syncs the caBundle and CertDir.
+// The caBundle is patched first, with the previous CAs still in it, so the API server trusts
+// a new serving certificate before the webhook server loads it.
+func (r *Rotator) rotate(ctx context.Context, now time.Time) error {
+	var secret *corev1.Secret
+	// Replicas race to create and renew the Secret, the loser retries with the winner's certificates
+	err := retry.OnError(retry.DefaultBackoff, func(err error) bool {
+		return apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err)
+	}, func() error {
+		var err error
+		secret, err = r.ensureSecret(ctx, now)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to ensure certificate Secret %s: %w", r.SecretKey, err)
+	}
+
+	if err := r.patchCABundle(ctx, secret.Data[caCertKey]); err != nil {
+		return fmt.Errorf("unable to patch caBundle of %s: %w", r.WebhookConfigurationName, err)
+	}
+	if err := r.writeCertDir(secret.Data); err != nil {
+		return fmt.Errorf("unable to write certificate to %s: %w", r.CertDir, err)
+	}
+	return nil
+}
+
+// ensureSecret creates or renews the certificates in the Secret
+func (r *Rotator) ensureSecret(ctx context.Context, now time.Time) (*corev1.Secret, error) {
+	logger := log.FromContext(ctx)
+
+	secret := &corev1.Secret{}
+	err := r.Client.Get(ctx, r.SecretKey, secret)
+	notFound := apierrors.IsNotFound(err)
+	if err != nil && !notFound {
+		return nil, err
+	}
+
+	data, changed, err := r.renew(secret.Data, now)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return secret, nil
+	}
+	secret.Data = data
+
+	if notFound {
+		logger.Info(fmt.Sprintf("Creating webhook certificate Secret %s", r.SecretKey))
+		secret.ObjectMeta = metav1.ObjectMeta{Name: r.SecretKey.Name, Namespace: r.SecretKey.Namespace}
+		secret.Type = corev1.SecretTypeTLS
+		return secret, r.Client.Create(ctx, secret)
+	}
+	logger.Info(fmt.Sprintf("Renewing webhook certificate in Secret %s", r.SecretKey))
+	return secret, r.Client.Update(ctx, secret)
+}
+
+// renew returns the Secret data with a CA and serving certificate that are valid at now,
+// and whether anything was renewed
+func (r *Rotator) renew(data map[string][]byte, now time.Time) (map[string][]byte, bool, error) {
+	renewed := make(map[string][]byte, len(data))
+	for k, v := range data {
+		renewed[k] = v
+	}
+	changed := false
+
+	ca, err := parseKeyPair(data[caCertKey], data[caKeyKey])
+	if err != nil || needsRenewal(ca.cert, now) {
+		ca, err = newCA(r.ServiceName+"-ca", now, durationOr(r.CAValidity, 10*365*24*time.Hour))
+		if err != nil {
+			return nil, false, err
+		}
+		renewed[caCertKey] = caBundle(ca, data[caCertKey], now)
+		renewed[caKeyKey] = ca.keyPEM
+		changed = true
+	}
+
+	dnsNames := r.dnsNames()
+	serving, err := parseKeyPair(data[certKey], data[keyKey])
+	if err != nil || needsRenewal(serving.cert, now) || !validFor(serving.cert, ca, dnsNames) {
+		serving, err = newServingCert(ca, dnsNames, now, durationOr(r.CertValidity, 365*24*time.Hour))
+		if err != nil {
+			return nil, false, err
+		}
+		renewed[certKey] = serving.certPEM
+		renewed[keyKey] = serving.keyPEM
+		changed = true
+	}
+
+	return renewed, changed, nil
+}
+
+// dnsNames are the names the API server may use to reach the webhook Service
+func (r *Rotator) dnsNames() []string {
+	service, namespace := r.ServiceName, r.SecretKey.Namespace
+	return []string{
+		fmt.Sprintf("%s.%s.svc", service, namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", service, namespace),
+		fmt.Sprintf("%s.%s", service, namespace),
+		service,
+	}
+}
+
+// writeCertDir writes the serving certificate to CertDir if it changed. Files are
+// replaced atomically, key first, so the webhook server's certwatcher reloads a
+// matching pair once the certificate is written.
+func (r *Rotator) writeCertDir(data map[string][]byte) error {
+	if err := os.MkdirAll(r.CertDir, 0o700); err != nil {
+		return err
+	}
+	for _, key := range []string{keyKey, certKey} {
+		path := filepath.Join(r.CertDir, key)
+		current, err := os.ReadFile(path)
+		if err == nil && bytes.Equal(current, data[key]) {
+			continue
+		}
+
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data[key], 0o600); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchCABundle sets the caBundle of every webhook of the ValidatingWebhookConfiguration
+func (r *Rotator) patchCABundle(ctx context.Context, bundle []byte) error {
+	logger := log.FromContext(ctx)
+
+	config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	err := r.Client.Get(ctx, types.NamespacedName{Name: r.WebhookConfigurationName}, config)
+	if apierrors.IsNotFound(err) {
+		// Patched on the next check once it is installed
+		logger.Info(fmt.Sprintf("ValidatingWebhookConfiguration %s not found, not patching caBundle", r.WebhookConfigurationName))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(config.DeepCopy())
+	changed := false
+	for i := range config.Webhooks {
+		if !bytes.Equal(config.Webhooks[i].ClientConfig.CABundle, bundle) {
+			config.Webhooks[i].ClientConfig.CABundle = bundle
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	logger.Info(fmt.Sprintf("Patching caBundle of ValidatingWebhookConfiguration %s", r.WebhookConfigurationName))
+	return r.Client.Patch(ctx, config, patch)
+}
+
+func durationOr(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
@@ -0,0 +1,117 @@
+package certs
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("Rotator", func() {
+	const namespace = "default"
+
+	var (
+		ctx     context.Context
+		rotator *Rotator
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		config := &admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "eviction-webhook"},
+			Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "eviction.mydomain.com"}},
+		}
+		rotator = &Rotator{
+			Client:                   fake.NewClientBuilder().WithObjects(config).Build(),
+			SecretKey:                types.NamespacedName{Namespace: namespace, Name: "eviction-webhook-certs"},
+			ServiceName:              "eviction-webhook-service",
+			WebhookConfigurationName: "eviction-webhook",
+			CertDir:                  GinkgoT().TempDir(),
+		}
+	})
+
+	// served returns the certificate in CertDir after checking it is trusted by the caBundle
+	served := func() *x509.Certificate {
+		certPEM, err := os.ReadFile(filepath.Join(rotator.CertDir, "tls.crt"))
+		Expect(err).NotTo(HaveOccurred())
+		keyPEM, err := os.ReadFile(filepath.Join(rotator.CertDir, "tls.key"))
+		Expect(err).NotTo(HaveOccurred())
+		serving, err := parseKeyPair(certPEM, keyPEM)
+		Expect(err).NotTo(HaveOccurred())
+
+		config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		Expect(rotator.Client.Get(ctx, types.NamespacedName{Name: "eviction-webhook"}, config)).To(Succeed())
+		roots := x509.NewCertPool()
+		Expect(roots.AppendCertsFromPEM(config.Webhooks[0].ClientConfig.CABundle)).To(BeTrue())
+		_, err = serving.cert.Verify(x509.VerifyOptions{
+			DNSName:     "eviction-webhook-service.default.svc",
+			Roots:       roots,
+			CurrentTime: serving.cert.NotBefore.Add(time.Hour),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return serving.cert
+	}
+
+	It("should bootstrap a certificate trusted by the webhook configuration", func() {
+		Expect(rotator.Bootstrap(ctx)).To(Succeed())
+		cert := served()
+
+		secret := &corev1.Secret{}
+		Expect(rotator.Client.Get(ctx, rotator.SecretKey, secret)).To(Succeed())
+		Expect(secret.Data).To(HaveKey("ca.key"))
+
+		By("keeping a certificate that does not need renewal")
+		Expect(rotator.Bootstrap(ctx)).To(Succeed())
+		Expect(served().Equal(cert)).To(BeTrue())
+	})
+
+	It("should renew the serving certificate before it expires", func() {
+		Expect(rotator.Bootstrap(ctx)).To(Succeed())
+		cert := served()
+
+		Expect(rotator.rotate(ctx, time.Now().Add(300*24*time.Hour))).To(Succeed())
+		Expect(served().Equal(cert)).To(BeFalse())
+	})
+
+	It("should keep trusting the previous CA after rotating it", func() {
+		Expect(rotator.Bootstrap(ctx)).To(Succeed())
+		cert := served()
+
+		Expect(rotator.rotate(ctx, time.Now().Add(7*365*24*time.Hour))).To(Succeed())
+		Expect(served().Equal(cert)).To(BeFalse())
+
+		secret := &corev1.Secret{}
+		Expect(rotator.Client.Get(ctx, rotator.SecretKey, secret)).To(Succeed())
+		roots := x509.NewCertPool()
+		Expect(roots.AppendCertsFromPEM(secret.Data["ca.crt"])).To(BeTrue())
+		_, err := cert.Verify(x509.VerifyOptions{Roots: roots, CurrentTime: cert.NotBefore.Add(time.Hour)})
+		Expect(err).NotTo(HaveOccurred())
+	})
+	It("should not serve a certificate before the caBundle trusts it", func() {
+		config := &admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "eviction-webhook"},
+			Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "eviction.mydomain.com"}},
+		}
+		rotator.Client = fake.NewClientBuilder().WithObjects(config).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				return errors.New("patch failed")
+			},
+		}).Build()
+
+		Expect(rotator.Bootstrap(ctx)).NotTo(Succeed())
+		_, err := os.Stat(filepath.Join(rotator.CertDir, "tls.crt"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+// cordonedDeletionCost is the deletion cost of pods on cordoned nodes, below the cost of any other pod
+const cordonedDeletionCost = -1000
+
+// setDeletionCosts annotates the pods with a pod-deletion-cost before a Deployment is scaled
+// down, so the ReplicaSet controller removes the pods on cordoned nodes, and by policy the
+// oldest, instead of the freshly surged ones. Costs set by someone else are left alone.
+func (r *PDBWatcherReconciler) setDeletionCosts(ctx context.Context, policy myappsv1.PodDeletionCostPolicy, pods []corev1.Pod) error {
+	if policy == "" || policy == myappsv1.PodDeletionCostNone {
+		return nil
+	}
+
+	live := livePods(pods)
+	sort.SliceStable(live, func(i, j int) bool {
+		return live[i].CreationTimestamp.Before(&live[j].CreationTimestamp)
+	})
+	nodes := make(map[string]*corev1.Node)
+	for rank := range live {
+		pod := &live[rank]
+		node, err := r.podNode(ctx, pod, nodes)
+		if err != nil {
+			return err
+		}
+
+		cost := 0
+		if policy == myappsv1.PodDeletionCostCordonedThenOldest {
+			cost = rank // Oldest first
+		}
+		if node != nil && (nodeCordoned(node) || nodeTaintedNoExecute(node)) {
+			cost = cordonedDeletionCost
+		}
+		if err := r.patchDeletionCost(ctx, pod, strconv.Itoa(cost)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearDeletionCosts removes the deletion costs set by the controller, once the ReplicaSet
+// controller has picked the pods to remove for replicas
+func (r *PDBWatcherReconciler) clearDeletionCosts(ctx context.Context, pods []corev1.Pod, replicas int32) error {
+	live := livePods(pods)
+	if int32(len(live)) > replicas {
+		return nil // Still scaling down
+	}
+	for i := range live {
+		if err := r.patchDeletionCost(ctx, &live[i], ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchDeletionCost sets the pod's deletion cost, or removes it if cost is empty, unless it
+// was set by someone else
+func (r *PDBWatcherReconciler) patchDeletionCost(ctx context.Context, pod *corev1.Pod, cost string) error {
+	current, hasCost := pod.Annotations[corev1.PodDeletionCost]
+	_, managed := pod.Annotations[myappsv1.DeletionCostManagedAnnotation]
+	if hasCost && !managed {
+		return nil // Set by someone else
+	}
+	if current == cost && (managed || cost == "") {
+		return nil
+	}
+
+	patch := client.MergeFrom(pod.DeepCopy())
+	if cost == "" {
+		delete(pod.Annotations, corev1.PodDeletionCost)
+		delete(pod.Annotations, myappsv1.DeletionCostManagedAnnotation)
+	} else {
+		if pod.Annotations == nil {
+			pod.Annotations = make(map[string]string)
+		}
+		pod.Annotations[corev1.PodDeletionCost] = cost
+		pod.Annotations[myappsv1.DeletionCostManagedAnnotation] = "true"
+	}
+	err := r.Patch(ctx, pod, patch)
+	if errors.IsNotFound(err) {
+		return nil // Pod is gone
+	}
+	return err
+}
+
+// podNode returns the node of the pod, nil if it isn't scheduled or the node is gone.
+// Nodes are cached in nodes by name.
+func (r *PDBWatcherReconciler) podNode(ctx context.Context, pod *corev1.Pod, nodes map[string]*corev1.Node) (*corev1.Node, error) {
+	if pod.Spec.NodeName == "" {
+		return nil, nil
+	}
+	if node, ok := nodes[pod.Spec.NodeName]; ok {
+		return node, nil
+	}
+
+	node := &corev1.Node{}
+	err := r.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, node)
+	if errors.IsNotFound(err) {
+		node = nil
+	} else if err != nil {
+		return nil, err
+	}
+	nodes[pod.Spec.NodeName] = node
+	return node, nil
+}
+
+// livePods returns the pods that are not terminating
+func livePods(pods []corev1.Pod) []corev1.Pod {
+	var live []corev1.Pod
+	for _, pod := range pods {
+		if pod.DeletionTimestamp == nil {
+			live = append(live, pod)
+		}
+	}
+	return live
+}
+
+// nodeTaintedNoExecute reports whether the node has a NoExecute taint, its pods are being evicted
+func nodeTaintedNoExecute(node *corev1.Node) bool {
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == corev1.TaintEffectNoExecute {
+			return true
+		}
+	}
+	return false
+}
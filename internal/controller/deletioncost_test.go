@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+var _ = Describe("Pod deletion cost", func() {
+	Context("When reverting the surge of a Deployment", func() {
+		ctx := context.Background()
+		now := time.Now()
+
+		var reconciler *PDBWatcherReconciler
+		var pods []corev1.Pod
+
+		pod := func(name, node string, age time.Duration, annotations map[string]string) *corev1.Pod {
+			return &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              name,
+					Namespace:         "default",
+					CreationTimestamp: metav1.NewTime(now.Add(-age)),
+					Annotations:       annotations,
+				},
+				Spec: corev1.PodSpec{NodeName: node},
+			}
+		}
+		costs := func() map[string]string {
+			list := &corev1.PodList{}
+			Expect(reconciler.List(ctx, list, client.InNamespace("default"))).To(Succeed())
+			pods = list.Items
+			costs := make(map[string]string)
+			for _, pod := range list.Items {
+				if cost, ok := pod.Annotations[corev1.PodDeletionCost]; ok {
+					costs[pod.Name] = cost
+				}
+			}
+			return costs
+		}
+
+		BeforeEach(func() {
+			cordoned := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cordoned"}, Spec: corev1.NodeSpec{Unschedulable: true}}
+			ready := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "ready"}}
+			reconciler = &PDBWatcherReconciler{Client: fake.NewClientBuilder().WithObjects(
+				cordoned, ready,
+				pod("draining", "cordoned", time.Minute, nil),
+				pod("old", "ready", time.Hour, nil),
+				pod("surged", "ready", time.Minute, nil),
+				pod("pinned", "ready", 2*time.Hour, map[string]string{corev1.PodDeletionCost: "100"}),
+			).Build()}
+			costs()
+		})
+
+		It("should remove the pods on cordoned nodes first, then the oldest", func() {
+			Expect(reconciler.setDeletionCosts(ctx, v1.PodDeletionCostCordonedThenOldest, pods)).To(Succeed())
+			Expect(costs()).To(Equal(map[string]string{
+				"draining": "-1000",
+				"old":      "1",
+				"surged":   "3",
+				"pinned":   "100", // Set by someone else
+			}))
+		})
+
+		It("should only clean up its own costs once the ReplicaSet picked the pods to remove", func() {
+			Expect(reconciler.setDeletionCosts(ctx, v1.PodDeletionCostCordonedFirst, pods)).To(Succeed())
+			Expect(costs()).To(HaveKeyWithValue("draining", "-1000"))
+
+			Expect(reconciler.clearDeletionCosts(ctx, pods, 3)).To(Succeed())
+			Expect(costs()).To(HaveLen(4))
+
+			Expect(reconciler.clearDeletionCosts(ctx, pods, 4)).To(Succeed())
+			Expect(costs()).To(Equal(map[string]string{"pinned": "100"}))
+		})
+
+		It("should leave the pods alone without a policy", func() {
+			Expect(reconciler.setDeletionCosts(ctx, "", pods)).To(Succeed())
+			Expect(costs()).To(Equal(map[string]string{"pinned": "100"}))
+		})
+	})
+})
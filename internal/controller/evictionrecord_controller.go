@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+// EvictionRecordReconciler deletes EvictionRecords once they outlive their TTL
+type EvictionRecordReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	TTL    time.Duration // How long records are kept, extended to the PDBWatcher's eviction window
+}
+
+// +kubebuilder:rbac:groups=apps.mydomain.com,resources=evictionrecords,verbs=get;list;watch;delete
+
+func (r *EvictionRecordReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	// Fetch the EvictionRecord instance
+	record := &myappsv1.EvictionRecord{}
+	err := r.Get(ctx, req.NamespacedName, record)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil // EvictionRecord already deleted, nothing to do
+		}
+		return ctrl.Result{}, err // Error fetching EvictionRecord
+	}
+
+	// Never prune records the PDBWatcher still counts
+	ttl := r.TTL
+	pdbWatcher := &myappsv1.PDBWatcher{}
+	err = r.Get(ctx, types.NamespacedName{Name: record.Spec.PDBWatcherName, Namespace: record.Namespace}, pdbWatcher)
+	if err == nil && evictionWindow(pdbWatcher) > ttl {
+		ttl = evictionWindow(pdbWatcher)
+	} else if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err // Error fetching PDBWatcher
+	}
+
+	// Age the record from its latest coalesced attempt, not from when it was created
+	evictionTime := record.Spec.EvictionTime.Time
+	if evictionTime.IsZero() {
+		evictionTime = record.CreationTimestamp.Time
+	}
+	age := time.Since(evictionTime)
+	if age < ttl {
+		return ctrl.Result{RequeueAfter: ttl - age}, nil
+	}
+
+	err = r.Delete(ctx, record)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	logger.Info(fmt.Sprintf("Deleted EvictionRecord %s/%s after %s", record.Namespace, record.Name, age.Round(time.Second)))
+
+	return ctrl.Result{}, nil
+}
+
+// listEvictionRecords returns the EvictionRecords attributed to a PDBWatcher
+func listEvictionRecords(ctx context.Context, c client.Client, pdbWatcher *myappsv1.PDBWatcher) ([]myappsv1.EvictionRecord, error) {
+	recordList := &myappsv1.EvictionRecordList{}
+	err := c.List(ctx, recordList, client.InNamespace(pdbWatcher.Namespace), client.MatchingLabels{myappsv1.PDBWatcherLabel: pdbWatcher.Name})
+	if err != nil {
+		return nil, err
+	}
+	return recordList.Items, nil
+}
+
+func (r *EvictionRecordReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&myappsv1.EvictionRecord{}).
+		Complete(r)
+}
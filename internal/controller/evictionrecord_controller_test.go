@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+var _ = Describe("EvictionRecord Controller", func() {
+	Context("When reconciling a record", func() {
+		const namespace = "default"
+
+		ctx := context.Background()
+
+		It("should keep records younger than the TTL and delete expired ones", func() {
+			record := &v1.EvictionRecord{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "evicted-pod-record",
+					Namespace: namespace,
+					Labels:    map[string]string{v1.PDBWatcherLabel: "missing-watcher"},
+				},
+				Spec: v1.EvictionRecordSpec{
+					PDBWatcherName: "missing-watcher",
+					EvictionLog: v1.EvictionLog{
+						PodName:      "evicted-pod",
+						EvictionTime: metav1.Now(),
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, record)).To(Succeed())
+
+			By("reconciling with a TTL that has not passed")
+			controllerReconciler := &EvictionRecordReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				TTL:    time.Hour,
+			}
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(record)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(record), record)).To(Succeed())
+
+			By("reconciling with an expired TTL")
+			controllerReconciler.TTL = 0
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(record)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(record), record))).To(BeTrue())
+		})
+	})
+})
+
+var _ = Describe("EvictionRecord TTL", func() {
+	It("should age records from their latest eviction", func() {
+		scheme := runtime.NewScheme()
+		Expect(v1.AddToScheme(scheme)).To(Succeed())
+		record := &v1.EvictionRecord{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "coalesced-pod-record",
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-2 * time.Hour)),
+			},
+			Spec: v1.EvictionRecordSpec{
+				PDBWatcherName: "missing-watcher",
+				EvictionLog: v1.EvictionLog{
+					PodName:      "coalesced-pod",
+					EvictionTime: metav1.NewTime(time.Now().Add(-time.Minute)),
+					Attempts:     3,
+				},
+			},
+		}
+		controllerReconciler := &EvictionRecordReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(record).Build(),
+			TTL:    time.Hour,
+		}
+
+		result, err := controllerReconciler.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(record)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically("~", 59*time.Minute, time.Minute))
+		Expect(controllerReconciler.Get(context.Background(), client.ObjectKeyFromObject(record), record)).To(Succeed())
+	})
+})
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+// maxFailuresInMessage bounds the failing containers listed in the Degraded condition
+const maxFailuresInMessage = 5
+
+// failingReasons are the container waiting reasons of pods that won't become Ready on
+// their own, surged pods of the same template would fail the same way
+var failingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// podFailures returns the failing containers of the pods that aren't Ready, as pod/container: reason
+func podFailures(pods []corev1.Pod) []string {
+	var failures []string
+	for _, pod := range livePods(pods) {
+		if podReady(&pod) {
+			continue
+		}
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			if status.State.Waiting != nil && failingReasons[status.State.Waiting.Reason] {
+				failures = append(failures, fmt.Sprintf("%s/%s: %s", pod.Name, status.Name, status.State.Waiting.Reason))
+			}
+		}
+	}
+	sort.Strings(failures)
+	return failures
+}
+
+// degradedCondition returns the Degraded condition, True when pods of the workload are
+// failing, so the PDB blocks evictions for a reason a surge won't fix
+func degradedCondition(pdb *policyv1.PodDisruptionBudget, pods []corev1.Pod, generation int64) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               myappsv1.ConditionDegraded,
+		Status:             metav1.ConditionFalse,
+		Reason:             myappsv1.DegradedPodsHealthy,
+		Message:            "No pod of the workload is failing",
+		ObservedGeneration: generation,
+	}
+	failures := podFailures(pods)
+	if len(failures) == 0 {
+		return condition
+	}
+
+	listed := failures
+	if len(listed) > maxFailuresInMessage {
+		listed = append(listed[:maxFailuresInMessage:maxFailuresInMessage], fmt.Sprintf("and %d more", len(failures)-maxFailuresInMessage))
+	}
+	evictable := "the failing pods can't be evicted either while the PDB is below its budget, unless its unhealthyPodEvictionPolicy is AlwaysAllow"
+	if pdb.Spec.UnhealthyPodEvictionPolicy != nil && *pdb.Spec.UnhealthyPodEvictionPolicy == policyv1.AlwaysAllow {
+		evictable = "the failing pods can be evicted, the healthy ones are blocked until the failure is fixed"
+	}
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = myappsv1.DegradedPodsFailing
+	condition.Message = fmt.Sprintf("Not surging, new pods would fail like %s; %s", strings.Join(listed, ", "), evictable)
+	return condition
+}
+
+// podReady reports whether the pod's Ready condition is True
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
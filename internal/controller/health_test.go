@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+var _ = Describe("Workload health", func() {
+	Context("When the PDB allows no disruptions", func() {
+		pod := func(name string, ready bool, waiting string) corev1.Pod {
+			status := corev1.ConditionFalse
+			if ready {
+				status = corev1.ConditionTrue
+			}
+			pod := corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Status: corev1.PodStatus{
+					Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+				},
+			}
+			if waiting != "" {
+				pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+					Name:  "app",
+					State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: waiting}},
+				}}
+			}
+			return pod
+		}
+		pdb := &policyv1.PodDisruptionBudget{}
+
+		It("should be at budget when pods are healthy or still starting", func() {
+			condition := degradedCondition(pdb, []corev1.Pod{pod("ready", true, ""), pod("starting", false, "ContainerCreating")}, 1)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(v1.DegradedPodsHealthy))
+		})
+
+		It("should be degraded when pods are failing, listing the failing containers", func() {
+			pods := []corev1.Pod{pod("ready", true, ""), pod("crashing", false, "CrashLoopBackOff"), pod("pulling", false, "ImagePullBackOff")}
+			condition := degradedCondition(pdb, pods, 1)
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal(v1.DegradedPodsFailing))
+			Expect(condition.Message).To(ContainSubstring("crashing/app: CrashLoopBackOff, pulling/app: ImagePullBackOff"))
+			Expect(condition.Message).To(ContainSubstring("unless its unhealthyPodEvictionPolicy is AlwaysAllow"))
+
+			alwaysAllow := policyv1.AlwaysAllow
+			condition = degradedCondition(&policyv1.PodDisruptionBudget{Spec: policyv1.PodDisruptionBudgetSpec{UnhealthyPodEvictionPolicy: &alwaysAllow}}, pods, 1)
+			Expect(condition.Message).To(ContainSubstring("the failing pods can be evicted"))
+		})
+	})
+})
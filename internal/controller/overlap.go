@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+// pdbOverlap is the other PDBs selecting pods of the watched PDB
+type pdbOverlap struct {
+	others []*policyv1.PodDisruptionBudget // Sorted by name
+	pods   int                             // Pods covered by more than one PDB
+}
+
+// overlappingPDBs finds the PDBs other than pdb that select any of its pods
+func (r *PDBWatcherReconciler) overlappingPDBs(ctx context.Context, pdb *policyv1.PodDisruptionBudget, pods []corev1.Pod) (pdbOverlap, error) {
+	pdbList := &policyv1.PodDisruptionBudgetList{}
+	err := r.List(ctx, pdbList, client.InNamespace(pdb.Namespace))
+	if err != nil {
+		return pdbOverlap{}, err
+	}
+
+	var overlap pdbOverlap
+	selectors := make(map[string]labels.Selector)
+	for i := range pdbList.Items {
+		other := &pdbList.Items[i]
+		if other.Name == pdb.Name {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(other.Spec.Selector)
+		if err != nil {
+			continue // The disruption controller ignores invalid PDBs too
+		}
+		selectors[other.Name] = selector
+	}
+
+	covering := make(map[string]*policyv1.PodDisruptionBudget)
+	for _, pod := range pods {
+		overlapping := false
+		for i := range pdbList.Items {
+			other := &pdbList.Items[i]
+			if selector, ok := selectors[other.Name]; ok && selector.Matches(labels.Set(pod.Labels)) {
+				covering[other.Name] = other
+				overlapping = true
+			}
+		}
+		if overlapping {
+			overlap.pods++
+		}
+	}
+	for _, other := range covering {
+		overlap.others = append(overlap.others, other)
+	}
+	sort.Slice(overlap.others, func(i, j int) bool {
+		return overlap.others[i].Name < overlap.others[j].Name
+	})
+	return overlap, nil
+}
+
+// names returns the names of the other PDBs
+func (o pdbOverlap) names() []string {
+	names := make([]string, 0, len(o.others))
+	for _, other := range o.others {
+		names = append(names, other.Name)
+	}
+	return names
+}
+
+// overlapCondition returns the OverlappingPDBs condition, True when pods of pdb are covered by other PDBs
+func overlapCondition(pdb *policyv1.PodDisruptionBudget, overlap pdbOverlap, generation int64) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               myappsv1.ConditionOverlappingPDBs,
+		Status:             metav1.ConditionFalse,
+		Reason:             myappsv1.OverlappingPDBsNone,
+		Message:            fmt.Sprintf("Pods are only covered by PDB %s", pdb.Name),
+		ObservedGeneration: generation,
+	}
+	if overlap.pods > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = myappsv1.OverlappingPDBsMultiple
+		condition.Message = fmt.Sprintf("%d pods covered by PDB %s are also covered by %s, the eviction API refuses to evict them",
+			overlap.pods, pdb.Name, strings.Join(overlap.names(), ", "))
+	}
+	return condition
+}
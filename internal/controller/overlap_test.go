@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+var _ = Describe("Overlapping PDBs", func() {
+	Context("When other PDBs select the watched pods", func() {
+		ctx := context.Background()
+		pdb := func(name string, matchLabels map[string]string) *policyv1.PodDisruptionBudget {
+			return &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: matchLabels}},
+			}
+		}
+		pod := func(name, tier string) corev1.Pod {
+			return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"app": "example", "tier": tier}}}
+		}
+		watched := pdb("example-pdb", map[string]string{"app": "example"})
+		pods := []corev1.Pod{pod("web-1", "web"), pod("web-2", "web"), pod("db-1", "db")}
+
+		It("should count the pods covered by more than one PDB", func() {
+			reconciler := &PDBWatcherReconciler{Client: fake.NewClientBuilder().WithObjects(
+				watched,
+				pdb("web-pdb", map[string]string{"tier": "web"}),
+				pdb("other-pdb", map[string]string{"app": "other"}),
+			).Build()}
+
+			overlap, err := reconciler.overlappingPDBs(ctx, watched, pods)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(overlap.pods).To(Equal(2))
+			Expect(overlap.names()).To(Equal([]string{"web-pdb"}))
+
+			condition := overlapCondition(watched, overlap, 1)
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal(v1.OverlappingPDBsMultiple))
+			Expect(condition.Message).To(ContainSubstring("2 pods covered by PDB example-pdb are also covered by web-pdb"))
+		})
+
+		It("should report no overlap when only the watched PDB selects the pods", func() {
+			reconciler := &PDBWatcherReconciler{Client: fake.NewClientBuilder().WithObjects(watched).Build()}
+
+			overlap, err := reconciler.overlappingPDBs(ctx, watched, pods)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(overlapCondition(watched, overlap, 1).Status).To(Equal(metav1.ConditionFalse))
+		})
+	})
+})
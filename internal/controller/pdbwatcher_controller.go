@@ -3,38 +3,51 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+	"github.com/Javier090/k8s-pdb-autoscaler/internal/metrics"
+	"github.com/Javier090/k8s-pdb-autoscaler/internal/tracing"
 )
 
 // PDBWatcherReconciler reconciles a PDBWatcher object
 type PDBWatcherReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
-	Recorder record.EventRecorder
+	Recorder events.EventRecorder
 }
 
 // +kubebuilder:rbac:groups=apps.mydomain.com,resources=pdbwatchers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps.mydomain.com,resources=pdbwatchers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=apps.mydomain.com,resources=pdbwatchers/finalizers,verbs=update
+// +kubebuilder:rbac:groups=apps.mydomain.com,resources=evictionrecords,verbs=get;list;watch
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
 // +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 
 func (r *PDBWatcherReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -44,24 +57,25 @@ func (r *PDBWatcherReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	err := r.Get(ctx, req.NamespacedName, pdbWatcher)
 	if err != nil {
 		if errors.IsNotFound(err) {
+			metrics.DeletePDBWatcher(req.Namespace, req.Name)
 			return ctrl.Result{}, nil // PDBWatcher not found, could be deleted, nothing to do
 		}
-		return ctrl.Result{}, err // Error fetching PDBWatcher
+		return ctrl.Result{}, reconcileError("GetPDBWatcher", err) // Error fetching PDBWatcher
 	}
 
 	// Check for conflicts with other PDBWatchers
 	conflictWatcherList := &myappsv1.PDBWatcherList{}
 	err = r.List(ctx, conflictWatcherList, &client.ListOptions{Namespace: pdbWatcher.Namespace})
 	if err != nil {
-		return ctrl.Result{}, err // Error listing PDBWatchers
+		return ctrl.Result{}, reconcileError("ListPDBWatchers", err) // Error listing PDBWatchers
 	}
 
 	for _, watcher := range conflictWatcherList.Items {
 		if watcher.Name != pdbWatcher.Name && watcher.Spec.PDBName == pdbWatcher.Spec.PDBName {
 			// Conflict detected
 			errMsg := fmt.Sprintf("PDB %s is already being watched by another PDBWatcher %s", pdbWatcher.Spec.PDBName, watcher.Name)
-			r.Recorder.Event(pdbWatcher, corev1.EventTypeWarning, "Conflict", errMsg)
-			return ctrl.Result{}, fmt.Errorf(errMsg)
+			r.Recorder.Eventf(pdbWatcher, &watcher, corev1.EventTypeWarning, "Conflict", "Watch", errMsg)
+			return ctrl.Result{}, reconcileError("Conflict", fmt.Errorf(errMsg))
 		}
 	}
 
@@ -69,83 +83,163 @@ func (r *PDBWatcherReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	pdb := &policyv1.PodDisruptionBudget{}
 	err = r.Get(ctx, types.NamespacedName{Name: pdbWatcher.Spec.PDBName, Namespace: pdbWatcher.Namespace}, pdb)
 	if err != nil {
-		return ctrl.Result{}, err // Error fetching PDB
+		return ctrl.Result{}, reconcileError("GetPDB", err) // Error fetching PDB
 	}
 
 	// Check if PDB overlaps with multiple deployments
 	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
 	if err != nil {
-		return ctrl.Result{}, err // Error converting label selector
+		return ctrl.Result{}, reconcileError("InvalidSelector", err) // Error converting label selector
 	}
 
 	podList := &corev1.PodList{}
 	err = r.List(ctx, podList, &client.ListOptions{Namespace: pdbWatcher.Namespace, LabelSelector: selector})
 	if err != nil {
-		return ctrl.Result{}, err // Error listing pods
+		return ctrl.Result{}, reconcileError("ListPods", err) // Error listing pods
 	}
 
-	deploymentMap := make(map[string]struct{})
+	// Map of workloads owning the selected pods, keyed by kind and name
+	workloadMap := make(map[workloadKey]struct{})
 	for _, pod := range podList.Items {
 		for _, ownerRef := range pod.OwnerReferences {
-			if ownerRef.Kind == "ReplicaSet" {
+			switch ownerRef.Kind {
+			case "ReplicaSet":
 				replicaSet := &appsv1.ReplicaSet{}
 				err = r.Get(ctx, types.NamespacedName{Name: ownerRef.Name, Namespace: pdbWatcher.Namespace}, replicaSet)
 				if err != nil {
-					return ctrl.Result{}, err // Error fetching ReplicaSet
+					return ctrl.Result{}, reconcileError("GetReplicaSet", err) // Error fetching ReplicaSet
 				}
 
 				// Get the Deployment that owns this ReplicaSet
 				for _, rsOwnerRef := range replicaSet.OwnerReferences {
-					if rsOwnerRef.Kind == "Deployment" {
-						deploymentMap[rsOwnerRef.Name] = struct{}{}
+					if rsOwnerRef.Kind == myappsv1.DeploymentKind {
+						workloadMap[workloadKey{kind: myappsv1.DeploymentKind, name: rsOwnerRef.Name}] = struct{}{}
 					}
 				}
+			case myappsv1.StatefulSetKind:
+				workloadMap[workloadKey{kind: myappsv1.StatefulSetKind, name: ownerRef.Name}] = struct{}{}
 			}
 		}
 	}
 
-	// If multiple deployments are found, log a warning and return an error
-	if len(deploymentMap) > 1 {
-		r.Recorder.Event(pdbWatcher, corev1.EventTypeWarning, "MultipleDeployments", "PDB overlaps with multiple deployments")
-		return ctrl.Result{}, fmt.Errorf("PDB %s/%s overlaps with multiple deployments", pdbWatcher.Namespace, pdbWatcher.Spec.PDBName)
+	// If multiple workloads are found, log a warning and return an error
+	if len(workloadMap) > 1 {
+		r.Recorder.Eventf(pdbWatcher, pdb, corev1.EventTypeWarning, "MultipleDeployments", "Watch", "PDB overlaps with multiple deployments: %v", workloadMap)
+		return ctrl.Result{}, reconcileError("MultipleWorkloads", fmt.Errorf("PDB %s/%s overlaps with multiple deployments", pdbWatcher.Namespace, pdbWatcher.Spec.PDBName))
 	}
 
-	// Determine the deployment name
-	deploymentName := pdbWatcher.Spec.DeploymentName
-	if deploymentName == "" && len(deploymentMap) == 1 {
-		for name := range deploymentMap {
-			deploymentName = name
+	// Determine the workload kind and name
+	target := workloadKey{kind: pdbWatcher.Spec.TargetKind, name: pdbWatcher.Spec.DeploymentName}
+	if target.name == "" && len(workloadMap) == 1 {
+		for key := range workloadMap {
+			target = key
 		}
 	}
 
-	// Log the deployment map and deployment name for debugging
-	logger.Info(fmt.Sprintf("Deployment map: %v", deploymentMap))
-	logger.Info(fmt.Sprintf("Determined Deployment name: %s", deploymentName))
+	// Log the workload map and workload name for debugging
+	logger.Info(fmt.Sprintf("Workload map: %v", workloadMap))
+	logger.Info(fmt.Sprintf("Determined workload: %s", target))
 
 	// Validate the deployment name
-	if deploymentName == "" {
+	if target.name == "" {
 		errMsg := "Deployment name is empty"
 		logger.Error(fmt.Errorf(errMsg), errMsg)
-		return ctrl.Result{}, fmt.Errorf(errMsg)
+		return ctrl.Result{}, reconcileError("MissingTarget", fmt.Errorf(errMsg))
 	}
 
-	// Fetch the Deployment
-	deployment := &appsv1.Deployment{}
-	err = r.Get(ctx, types.NamespacedName{Name: deploymentName, Namespace: pdbWatcher.Namespace}, deployment)
+	if target.kind == "" {
+		target.kind = myappsv1.DeploymentKind
+	}
+
+	// PDBWatchers sharing the workload, e.g. one per zone-specific PDB, leave its baseline to the oldest of them
+	pdbWatcher.Status.Workload = target.String()
+	peers := sharedWatchers(pdbWatcher, target, conflictWatcherList.Items)
+	previousOwner := pdbWatcher.Status.BaselineOwner
+	owner := pdbWatcher
+	if len(peers) > 0 {
+		owner = baselineOwner(pdbWatcher, peers)
+		if pdbWatcher.Status.BaselineOwner != owner.Name {
+			logger.Info(fmt.Sprintf("%s is shared with %d other PDBWatchers, %s owns its baseline", target, len(peers), owner.Name))
+		}
+		pdbWatcher.Status.BaselineOwner = owner.Name
+	} else {
+		pdbWatcher.Status.BaselineOwner = ""
+	}
+	requested := pdbWatcher.Status.DesiredSurge
+
+	// Fetch the Deployment or StatefulSet
+	deployment, err := getWorkload(ctx, r.Client, target.kind, types.NamespacedName{Name: target.name, Namespace: pdbWatcher.Namespace})
+	if err != nil {
+		return ctrl.Result{}, reconcileError("GetWorkload", err) // Error fetching workload
+	}
+
+	// Aggregate the evictions recorded by the webhook
+	records, err := listEvictionRecords(ctx, r.Client, pdbWatcher)
 	if err != nil {
-		return ctrl.Result{}, err // Error fetching Deployment
+		return ctrl.Result{}, reconcileError("ListEvictionRecords", err) // Error listing EvictionRecords
+	}
+	// Records older than the window are kept until the EvictionRecord controller prunes them, but no longer count
+	evictions := aggregateEvictions(pdbWatcher, records, time.Now())
+	pdbWatcher.Status.EvictionCount = evictions.count(pdbWatcher.Spec.Trigger)
+	if evictions.lastEvictionTime != nil && (pdbWatcher.Status.LastEvictionTime == nil || evictions.lastEvictionTime.After(pdbWatcher.Status.LastEvictionTime.Time)) {
+		pdbWatcher.Status.LastEvictionTime = evictions.lastEvictionTime
+	}
+	minEvictions := triggerMinEvictions(pdbWatcher.Spec.Trigger)
+
+	// Prefer the PDBWatcher's maxSurge, then the Deployment strategy's
+	surge := pdbWatcher.Spec.MaxSurge
+	if surge == nil {
+		surge = deployment.maxSurge
+	}
+	maxSurge := surgeReplicas(surge, pdbWatcher.Status.MinReplicas)
+
+	// Refuse to surge when the PDB or the workload blocks evictions by design
+	meta.SetStatusCondition(&pdbWatcher.Status.Conditions, surgeEnabledCondition(pdb, surge, pdbWatcher.Generation))
+	// Or when pods are failing, surged pods of the same template would fail too
+	meta.SetStatusCondition(&pdbWatcher.Status.Conditions, degradedCondition(pdb, podList.Items, pdbWatcher.Generation))
+
+	// Pods covered by several PDBs can't be evicted at all, a surge doesn't help them
+	overlap, err := r.overlappingPDBs(ctx, pdb, livePods(podList.Items))
+	if err != nil {
+		return ctrl.Result{}, reconcileError("ListPDBs", err) // Error listing PDBs
+	}
+	meta.SetStatusCondition(&pdbWatcher.Status.Conditions, overlapCondition(pdb, overlap, pdbWatcher.Generation))
+	metrics.OverlappingPDBPods.WithLabelValues(pdbWatcher.Namespace, pdbWatcher.Name).Set(float64(overlap.pods))
+	if overlap.pods > 0 {
+		r.Recorder.Eventf(pdbWatcher, overlap.others[0], corev1.EventTypeWarning, "OverlappingPDBs", "Watch",
+			"%d pods of %s are covered by PDB %s and %s, the eviction API refuses to evict them",
+			overlap.pods, deployment, pdb.Name, strings.Join(overlap.names(), ", "))
+	}
+
+	// Track how long the PDB has been allowing disruptions, to stabilize reverts
+	now := time.Now()
+	if pdb.Status.DisruptionsAllowed == 0 {
+		pdbWatcher.Status.DisruptionsAllowedSince = nil
+	} else if pdbWatcher.Status.DisruptionsAllowedSince == nil {
+		pdbWatcher.Status.DisruptionsAllowedSince = &metav1.Time{Time: now}
+	}
+
+	// Only the baseline owner scales a shared workload, the others follow its baseline so any of them can take over
+	if owner != pdbWatcher && owner.Status.ResourceVersion != "" {
+		pdbWatcher.Status.MinReplicas = owner.Status.MinReplicas
+		pdbWatcher.Status.ScaledReplicas = owner.Status.ScaledReplicas
+		pdbWatcher.Status.ResourceVersion = deployment.GetResourceVersion()
+	}
+
+	// Taking the baseline over, the resource version changed since this PDBWatcher last followed the previous owner
+	if owner == pdbWatcher && keepsBaseline(pdbWatcher, previousOwner, deployment.Replicas()) {
+		logger.Info(fmt.Sprintf("Taking over the baseline of %s from %s", deployment, previousOwner))
+		pdbWatcher.Status.ResourceVersion = deployment.GetResourceVersion()
 	}
 
 	// Check if the resource version has changed or if it's empty (initial state)
-	if pdbWatcher.Status.ResourceVersion == "" || pdbWatcher.Status.ResourceVersion != deployment.ResourceVersion {
+	if owner == pdbWatcher && (pdbWatcher.Status.ResourceVersion == "" || pdbWatcher.Status.ResourceVersion != deployment.GetResourceVersion()) {
 		// The resource version has changed, which means someone else has modified the Deployment.
 		// To avoid conflicts, we update our status to reflect the new state and avoid making further changes.
-		pdbWatcher.Status.ResourceVersion = deployment.ResourceVersion
-		pdbWatcher.Status.MinReplicas = *deployment.Spec.Replicas
-		err = r.Status().Update(ctx, pdbWatcher)
+		err = r.adoptBaseline(ctx, pdbWatcher, deployment)
 		if err != nil {
 			logger.Error(err, "Failed to update PDBWatcher status")
-			return ctrl.Result{}, err
+			return ctrl.Result{}, reconcileError("UpdateStatus", err)
 		}
 		return ctrl.Result{}, nil
 	}
@@ -153,109 +247,558 @@ func (r *PDBWatcherReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	err = r.Status().Update(ctx, pdbWatcher)
 	if err != nil {
 		logger.Error(err, "Failed to update PDBWatcher status")
-		return ctrl.Result{}, err
+		return ctrl.Result{}, reconcileError("UpdateStatus", err)
+	}
+
+	// Clean up the deletion costs of the last revert once the ReplicaSet controller picked the pods to remove
+	if owner == pdbWatcher && pdbWatcher.Status.SurgeStartTime == nil {
+		err = r.clearDeletionCosts(ctx, podList.Items, deployment.Replicas())
+		if err != nil {
+			return ctrl.Result{}, reconcileError("PodDeletionCost", err) // Error annotating pods
+		}
 	}
+
 	// Log current state before checks
 	logger.Info(fmt.Sprintf("Checking PDB for %s: DisruptionsAllowed=%d, MinReplicas=%d", pdb.Name, pdb.Status.DisruptionsAllowed, pdbWatcher.Status.MinReplicas))
 
 	// Check the DisruptionsAllowed field
 	if pdb.Status.DisruptionsAllowed == 0 {
 		logger.Info(fmt.Sprintf("No disruptions allowed for %s, attempting to scale up", pdb.Name))
+
+		// The fewest replicas the PDB needs to allow the blocked evictions that are still pending, up to maxSurge
+		pending := max(evictions.pending(podList.Items), 1)
+		surgeBy, exact := requiredSurge(pdb, pdbWatcher.Status.MinReplicas, deployment.Replicas(), pending, maxSurge)
+
 		// Check if there are recent evictions
-		recentEviction := false
-		for _, log := range pdbWatcher.Status.EvictionLogs {
-			evictionTime, err := time.Parse(time.RFC3339, log.EvictionTime)
-			if err != nil {
-				logger.Error(err, "Failed to parse eviction time")
-				continue
+		if disabled := meta.FindStatusCondition(pdbWatcher.Status.Conditions, myappsv1.ConditionSurgeEnabled); disabled.Status == metav1.ConditionFalse {
+			if pdbWatcher.Status.EvictionCount > 0 {
+				r.Recorder.Eventf(pdb, pdbWatcher, corev1.EventTypeNormal, "EvictionsBlockedByDesign", "Observe",
+					"PDB %s blocked %d evictions of %s by design, not surging: %s", pdb.Name,
+					pdbWatcher.Status.EvictionCount, deployment, disabled.Message)
 			}
-
-			if time.Since(evictionTime) < 5*time.Minute {
-				recentEviction = true
-				break
+		} else if degraded := meta.FindStatusCondition(pdbWatcher.Status.Conditions, myappsv1.ConditionDegraded); pdbWatcher.Status.EvictionCount > 0 && degraded.Status == metav1.ConditionTrue {
+			r.event(pdbWatcher, deployment, corev1.EventTypeWarning, "SurgeSkipped", "ScaleUp",
+				"Not scaling %s up, PDB %s blocks evictions because pods are failing: %s", deployment, pdb.Name, degraded.Message)
+		} else if pdbWatcher.Status.EvictionCount > 0 && pdbWatcher.Status.EvictionCount < minEvictions {
+			r.event(pdbWatcher, deployment, corev1.EventTypeNormal, "SurgeSkipped", "ScaleUp",
+				"Not scaling %s up, %d of the %d evictions needed within the window were blocked", deployment,
+				pdbWatcher.Status.EvictionCount, minEvictions)
+		} else if cooldownEnd := cooldownEnd(pdbWatcher); pdbWatcher.Status.EvictionCount > 0 && now.Before(cooldownEnd) {
+			r.event(pdbWatcher, deployment, corev1.EventTypeNormal, "SurgeSkipped", "ScaleUp",
+				"Not scaling %s up, cooling down since the last revert until %s", deployment, cooldownEnd.Format(time.RFC3339))
+			return ctrl.Result{RequeueAfter: cooldownEnd.Sub(now)}, nil
+		} else if pdbWatcher.Status.EvictionCount > 0 && maxSurge <= 0 {
+			r.event(pdbWatcher, deployment, corev1.EventTypeWarning, "SurgeSkipped", "ScaleUp",
+				"Not scaling %s up, maxSurge %s resolves to 0 replicas", deployment, surge)
+		} else if pdbWatcher.Status.EvictionCount > 0 && !exact {
+			r.event(pdbWatcher, deployment, corev1.EventTypeWarning, "SurgeSkipped", "ScaleUp",
+				"Not scaling %s up, no surge up to %d replicas lets PDB %s allow %d disruptions", deployment, maxSurge, pdb.Name, pending)
+		} else if pdbWatcher.Status.EvictionCount > 0 {
+			// Add the fewest replicas the PDB needs to allow the blocked evictions
+			pdbWatcher.Status.DesiredSurge = surgeBy
+			if owner != pdbWatcher {
+				return r.requestSurge(ctx, pdbWatcher, owner, deployment, requested)
 			}
-		}
 
-		if recentEviction {
-			// Handle nil Deployment Strategy and MaxSurge
-			maxSurge := int32(1) // Default max surge value
-			if deployment.Spec.Strategy.RollingUpdate != nil && deployment.Spec.Strategy.RollingUpdate.MaxSurge != nil {
-				if deployment.Spec.Strategy.RollingUpdate.MaxSurge.Type == intstr.Int {
-					maxSurge = deployment.Spec.Strategy.RollingUpdate.MaxSurge.IntVal
-				} else if deployment.Spec.Strategy.RollingUpdate.MaxSurge.Type == intstr.String {
-					percentageStr := strings.TrimSuffix(deployment.Spec.Strategy.RollingUpdate.MaxSurge.StrVal, "%")
-					percentage, err := strconv.Atoi(percentageStr)
-					if err == nil {
-						maxSurge = (pdbWatcher.Status.MinReplicas * int32(percentage)) / 100
-					}
-				}
+			// Never shrink a surge in progress, its pods may still be starting
+			newReplicas := max(pdbWatcher.Status.MinReplicas+aggregateSurge(pdbWatcher.Spec.SurgeAggregation, surgeBy, peers), deployment.Replicas())
+
+			// Scale up the workload, in the trace of the eviction that caused it
+			traceCtx := ctx
+			if evictions.latest != nil {
+				traceCtx = tracing.ExtractAnnotations(ctx, evictions.latest.Annotations)
 			}
-			// Scale up the Deployment
-			newReplicas := pdbWatcher.Status.MinReplicas + maxSurge
-			deployment.Spec.Replicas = &newReplicas
+			ctx, span := tracing.Tracer().Start(traceCtx, "pdbwatcher.scale_up",
+				trace.WithAttributes(
+					attribute.String("k8s.namespace.name", pdbWatcher.Namespace),
+					attribute.String("pdbwatcher", pdbWatcher.Name),
+					attribute.String("workload", deployment.String()),
+					attribute.Int("replicas", int(newReplicas)),
+				))
+			defer span.End()
+			deployment.SetReplicas(newReplicas)
 			err = r.Update(ctx, deployment)
 			if err != nil {
-				return ctrl.Result{}, err
+				span.RecordError(err)
+				return ctrl.Result{}, reconcileError("ScaleUp", err)
 			}
 
-			// Save ResourceVersion to PDBWatcher status
-			pdbWatcher.Status.ResourceVersion = deployment.ResourceVersion
-
 			// Log the scaling action
-			logger.Info(fmt.Sprintf("Scaled up Deployment %s/%s to %d replicas", deployment.Namespace, deployment.Name, newReplicas))
+			logger.Info(fmt.Sprintf("Scaled up %s to %d replicas", deployment, newReplicas))
+
+			// Save ResourceVersion to PDBWatcher status, so the scale up isn't mistaken for an external change
+			pdbWatcher.Status.ResourceVersion = deployment.GetResourceVersion()
+			pdbWatcher.Status.ScaledReplicas = newReplicas
+			pdbWatcher.Status.ScaleDownStartTime = nil
+			started := pdbWatcher.Status.SurgeStartTime == nil
+			if started {
+				now := metav1.Now()
+				pdbWatcher.Status.SurgeStartTime = &now
+				metrics.SurgesStarted.WithLabelValues(pdbWatcher.Namespace, pdbWatcher.Name).Inc()
+			}
+			metrics.SurgedReplicas.WithLabelValues(pdbWatcher.Namespace, pdbWatcher.Name).Set(float64(newReplicas - pdbWatcher.Status.MinReplicas))
+			err = r.Status().Update(ctx, pdbWatcher)
+			if err != nil {
+				logger.Error(err, "Failed to update PDBWatcher status")
+				return ctrl.Result{}, reconcileError("UpdateStatus", err)
+			}
+			if started {
+				r.event(pdbWatcher, deployment, corev1.EventTypeNormal, "SurgeStarted", "ScaleUp",
+					"Scaled %s from %d to %d replicas, PDB %s blocked %d evictions", deployment,
+					pdbWatcher.Status.MinReplicas, newReplicas, pdb.Name, pdbWatcher.Status.EvictionCount)
+			}
 		}
 	}
 
-	// Process Eviction Logs
-	if len(pdbWatcher.Status.EvictionLogs) > 0 {
-		// Clear eviction logs after processing
-		pdbWatcher.Status.EvictionLogs = []myappsv1.EvictionLog{}
-		err = r.Status().Update(ctx, pdbWatcher)
-		if err != nil {
-			logger.Error(err, "Failed to update PDBWatcher status")
-			return ctrl.Result{}, err
+	// Surge for the peers sharing the workload until every one of them is done, whatever the owner's own PDB allows
+	if peerSurge := aggregateSurge(pdbWatcher.Spec.SurgeAggregation, 0, peers); owner == pdbWatcher && peerSurge > 0 {
+		if pdb.Status.DisruptionsAllowed > 0 {
+			pdbWatcher.Status.DesiredSurge = 0
 		}
+		return r.surgeForPeers(ctx, pdbWatcher, deployment, aggregateSurge(pdbWatcher.Spec.SurgeAggregation, pdbWatcher.Status.DesiredSurge, peers))
 	}
 
-	// Watch for changes in PDB to revert to original state
-	if pdb.Status.DisruptionsAllowed > 0 && *deployment.Spec.Replicas != pdbWatcher.Status.MinReplicas {
+	// Revert to the original state once no PDB sharing the workload needs the surge anymore
+	if surgeReleased(pdbWatcher, owner, pdb, peers) && (deployment.Replicas() != pdbWatcher.Status.MinReplicas || owner != pdbWatcher) {
 		// Check if the resource version has changed
-		if pdbWatcher.Status.ResourceVersion != deployment.ResourceVersion {
+		if owner == pdbWatcher && pdbWatcher.Status.ResourceVersion != deployment.GetResourceVersion() {
 			// Deployment has been modified externally, update the resource version and min replicas
-			pdbWatcher.Status.ResourceVersion = deployment.ResourceVersion
-			pdbWatcher.Status.MinReplicas = *deployment.Spec.Replicas
-			err = r.Status().Update(ctx, pdbWatcher)
+			err = r.adoptBaseline(ctx, pdbWatcher, deployment)
 			if err != nil {
 				logger.Error(err, "Failed to update PDBWatcher status")
-				return ctrl.Result{}, err
+				return ctrl.Result{}, reconcileError("UpdateStatus", err)
 			}
 			return ctrl.Result{}, nil
 		}
 
-		// Revert Deployment to the original state
-		deployment.Spec.Replicas = &pdbWatcher.Status.MinReplicas
+		// Hold the surge until it is stable, the PDB allows disruptions right after a surge succeeds
+		if revertTime := revertTime(pdbWatcher); now.Before(revertTime) {
+			logger.Info(fmt.Sprintf("Holding surge of %s until %s", deployment, revertTime.Format(time.RFC3339)))
+			return ctrl.Result{RequeueAfter: revertTime.Sub(now)}, nil
+		}
+
+		// Wait for the drain to finish, the PDB may allow disruptions while the node still hosts pods of the workload
+		if pdbWatcher.Spec.RevertWhen == myappsv1.RevertWhenDrainComplete {
+			draining, err := r.podsOnCordonedNodes(ctx, podList.Items)
+			if err != nil {
+				return ctrl.Result{}, reconcileError("GetNode", err) // Error fetching nodes
+			}
+			if draining > 0 || evictions.latest != nil {
+				logger.Info(fmt.Sprintf("Waiting for the drain to complete before reverting %s, %d pods on cordoned nodes, %d evictions within the window",
+					deployment, draining, evictions.attempts))
+				return ctrl.Result{RequeueAfter: drainPollInterval}, nil
+			}
+		}
+
+		// The PDB needs no surge anymore, the baseline owner reverts once no peer does either
+		pdbWatcher.Status.DesiredSurge = 0
+		if owner != pdbWatcher {
+			return r.requestSurge(ctx, pdbWatcher, owner, deployment, requested)
+		}
+
+		// Revert Deployment to the original state, stepwise if the PDBWatcher scales down in steps
+		replicas := pdbWatcher.Status.MinReplicas
+		if pdbWatcher.Spec.ScaleDown != nil {
+			if hold := scaleDownHold(pdbWatcher, evictions.latest, now); hold > 0 {
+				// A new eviction arrived, keep the surge while it is within the window and restart the stabilization window
+				if since := pdbWatcher.Status.DisruptionsAllowedSince; since == nil || evictions.latest.Spec.EvictionTime.After(since.Time) {
+					pdbWatcher.Status.DisruptionsAllowedSince = &metav1.Time{Time: now}
+					err = r.Status().Update(ctx, pdbWatcher)
+					if err != nil {
+						logger.Error(err, "Failed to update PDBWatcher status")
+						return ctrl.Result{}, reconcileError("UpdateStatus", err)
+					}
+					r.event(pdbWatcher, deployment, corev1.EventTypeNormal, "ScaleDownAborted", "ScaleDown",
+						"Stopped scaling %s down at %d replicas, a new eviction was requested", deployment, deployment.Replicas())
+				}
+				logger.Info(fmt.Sprintf("Holding scale-down of %s for %s, an eviction was requested since it started", deployment, hold))
+				return ctrl.Result{RequeueAfter: hold}, nil
+			}
+			if !pdbSettled(pdb) || pdb.Status.DisruptionsAllowed == 0 {
+				logger.Info(fmt.Sprintf("Waiting for PDB %s to settle and allow disruptions before scaling %s down", pdb.Name, deployment))
+				return ctrl.Result{RequeueAfter: scaleDownPollInterval}, nil
+			}
+			replicas = scaleDownStep(pdbWatcher.Spec.ScaleDown, deployment.Replicas(), pdbWatcher.Status.MinReplicas, pdb.Status.DisruptionsAllowed)
+		}
+		if deployment.kind == myappsv1.DeploymentKind {
+			err = r.setDeletionCosts(ctx, pdbWatcher.Spec.PodDeletionCost, podList.Items)
+			if err != nil {
+				return ctrl.Result{}, reconcileError("PodDeletionCost", err) // Error annotating pods
+			}
+		}
+		previous := deployment.Replicas()
+		deployment.SetReplicas(replicas)
 		err = r.Update(ctx, deployment)
 		if err != nil {
-			return ctrl.Result{}, err
+			return ctrl.Result{}, reconcileError("Revert", err)
 		}
 
 		// Log the scaling action
-		logger.Info(fmt.Sprintf("Reverted Deployment %s/%s to %d replicas", deployment.Namespace, deployment.Name, *deployment.Spec.Replicas))
+		logger.Info(fmt.Sprintf("Scaled %s down from %d to %d replicas", deployment, previous, replicas))
 
 		// Update ResourceVersion in PDBWatcher status
-		pdbWatcher.Status.ResourceVersion = deployment.ResourceVersion
+		pdbWatcher.Status.ResourceVersion = deployment.GetResourceVersion()
+		pdbWatcher.Status.ScaledReplicas = replicas
+		reverted := replicas == pdbWatcher.Status.MinReplicas
+		if reverted {
+			pdbWatcher.Status.LastRevertTime = &metav1.Time{Time: now}
+			endSurge(pdbWatcher, metrics.SurgesCompleted)
+		} else {
+			if pdbWatcher.Status.ScaleDownStartTime == nil {
+				pdbWatcher.Status.ScaleDownStartTime = &metav1.Time{Time: now}
+			}
+			metrics.SurgedReplicas.WithLabelValues(pdbWatcher.Namespace, pdbWatcher.Name).Set(float64(replicas - pdbWatcher.Status.MinReplicas))
+		}
 		err = r.Status().Update(ctx, pdbWatcher)
 		if err != nil {
 			logger.Error(err, "Failed to update PDBWatcher status")
-			return ctrl.Result{}, err
+			return ctrl.Result{}, reconcileError("UpdateStatus", err)
 		}
+		if !reverted {
+			r.event(pdbWatcher, deployment, corev1.EventTypeNormal, "ScaledDown", "ScaleDown",
+				"Scaled %s down from %d to %d replicas towards its baseline of %d", deployment,
+				previous, replicas, pdbWatcher.Status.MinReplicas)
+			return ctrl.Result{RequeueAfter: scaleDownPollInterval}, nil
+		}
+		r.event(pdbWatcher, deployment, corev1.EventTypeNormal, "Reverted", "ScaleDown",
+			"Reverted %s to %d replicas, PDB %s allows %d disruptions", deployment,
+			pdbWatcher.Status.MinReplicas, pdb.Name, pdb.Status.DisruptionsAllowed)
+	}
+
+	// Nothing is watched for the stabilization or the eviction window to pass, check back on the surge then
+	return ctrl.Result{RequeueAfter: surgeRequeue(pdbWatcher, evictions.latest, now)}, nil
+}
+
+// surgeForPeers keeps the workload surged by the replicas its peers request, combined with
+// the owner's own request
+func (r *PDBWatcherReconciler) surgeForPeers(ctx context.Context, pdbWatcher *myappsv1.PDBWatcher, target *workload, surge int32) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if pdbWatcher.Status.ResourceVersion != target.GetResourceVersion() {
+		// Workload has been modified externally, update the resource version and min replicas
+		err := r.adoptBaseline(ctx, pdbWatcher, target)
+		if err != nil {
+			logger.Error(err, "Failed to update PDBWatcher status")
+			return ctrl.Result{}, reconcileError("UpdateStatus", err)
+		}
+		return ctrl.Result{}, nil
 	}
 
+	newReplicas := pdbWatcher.Status.MinReplicas + surge
+	if target.Replicas() >= newReplicas {
+		// Already surged enough, never shrink a surge while a peer needs it
+		err := r.Status().Update(ctx, pdbWatcher)
+		if err != nil {
+			logger.Error(err, "Failed to update PDBWatcher status")
+			return ctrl.Result{}, reconcileError("UpdateStatus", err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	previous := target.Replicas()
+	target.SetReplicas(newReplicas)
+	err := r.Update(ctx, target)
+	if err != nil {
+		return ctrl.Result{}, reconcileError("ScaleUp", err)
+	}
+	logger.Info(fmt.Sprintf("Scaled up %s to %d replicas for the PDBWatchers sharing it", target, newReplicas))
+
+	pdbWatcher.Status.ResourceVersion = target.GetResourceVersion()
+	pdbWatcher.Status.ScaledReplicas = newReplicas
+	pdbWatcher.Status.ScaleDownStartTime = nil
+	started := pdbWatcher.Status.SurgeStartTime == nil
+	if started {
+		now := metav1.Now()
+		pdbWatcher.Status.SurgeStartTime = &now
+		metrics.SurgesStarted.WithLabelValues(pdbWatcher.Namespace, pdbWatcher.Name).Inc()
+	}
+	metrics.SurgedReplicas.WithLabelValues(pdbWatcher.Namespace, pdbWatcher.Name).Set(float64(newReplicas - pdbWatcher.Status.MinReplicas))
+	err = r.Status().Update(ctx, pdbWatcher)
+	if err != nil {
+		logger.Error(err, "Failed to update PDBWatcher status")
+		return ctrl.Result{}, reconcileError("UpdateStatus", err)
+	}
+	if started {
+		r.event(pdbWatcher, target, corev1.EventTypeNormal, "SurgeStarted", "ScaleUp",
+			"Scaled %s from %d to %d replicas, requested by the PDBWatchers sharing it", target, previous, newReplicas)
+	}
 	return ctrl.Result{}, nil
 }
 
+// adoptBaseline takes the workload's current replicas as the baseline, after the
+// workload was first seen or changed by someone else, abandoning any surge
+func (r *PDBWatcherReconciler) adoptBaseline(ctx context.Context, pdbWatcher *myappsv1.PDBWatcher, target *workload) error {
+	external := pdbWatcher.Status.ResourceVersion != ""
+	previous := pdbWatcher.Status.MinReplicas
+
+	pdbWatcher.Status.ResourceVersion = target.GetResourceVersion()
+	pdbWatcher.Status.MinReplicas = target.Replicas()
+	pdbWatcher.Status.ScaledReplicas = target.Replicas()
+	endSurge(pdbWatcher, metrics.SurgesRolledBack)
+	err := r.Status().Update(ctx, pdbWatcher)
+	if err != nil {
+		return err
+	}
+
+	if external {
+		r.event(pdbWatcher, target, corev1.EventTypeNormal, "ExternalChangeDetected", "Observe",
+			"%s was changed by someone else, not scaling it this time", target)
+	}
+	if !external || previous != pdbWatcher.Status.MinReplicas {
+		r.event(pdbWatcher, target, corev1.EventTypeNormal, "BaselineUpdated", "UpdateBaseline",
+			"Baseline of %s set to %d replicas", target, pdbWatcher.Status.MinReplicas)
+	}
+	return nil
+}
+
+// podsOnCordonedNodes counts the pods that are not terminating yet on nodes cordoned for a drain
+func (r *PDBWatcherReconciler) podsOnCordonedNodes(ctx context.Context, pods []corev1.Pod) (int, error) {
+	nodes := make(map[string]*corev1.Node)
+	count := 0
+	for _, pod := range livePods(pods) {
+		node, err := r.podNode(ctx, &pod, nodes)
+		if err != nil {
+			return 0, err
+		}
+		if node != nil && nodeCordoned(node) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// event records an event on the PDBWatcher and on its target workload, each referencing the other
+func (r *PDBWatcherReconciler) event(pdbWatcher *myappsv1.PDBWatcher, target *workload, eventtype, reason, action, note string, args ...interface{}) {
+	r.Recorder.Eventf(pdbWatcher, target.Object, eventtype, reason, action, note, args...)
+	r.Recorder.Eventf(target.Object, pdbWatcher, eventtype, reason, action, note, args...)
+}
+
+// reconcileError counts a failed reconcile of a PDBWatcher by the step that failed
+func reconcileError(reason string, err error) error {
+	metrics.ReconcileErrors.WithLabelValues("pdbwatcher", reason).Inc()
+	return err
+}
+
+// endSurge clears the surge in progress, if any, counting it in ended
+func endSurge(pdbWatcher *myappsv1.PDBWatcher, ended *prometheus.CounterVec) {
+	if pdbWatcher.Status.SurgeStartTime == nil {
+		return
+	}
+	ended.WithLabelValues(pdbWatcher.Namespace, pdbWatcher.Name).Inc()
+	metrics.SurgeDuration.WithLabelValues(pdbWatcher.Namespace).Observe(time.Since(pdbWatcher.Status.SurgeStartTime.Time).Seconds())
+	metrics.SurgedReplicas.WithLabelValues(pdbWatcher.Namespace, pdbWatcher.Name).Set(0)
+	pdbWatcher.Status.SurgeStartTime = nil
+	pdbWatcher.Status.ScaleDownStartTime = nil
+}
+
+// workloadKey identifies a workload within the PDBWatcher's namespace
+type workloadKey struct {
+	kind string
+	name string
+}
+
+func (k workloadKey) String() string {
+	return k.kind + "/" + k.name
+}
+
+// windowEvictions summarises a PDBWatcher's EvictionRecords
+type windowEvictions struct {
+	attempts         int32                    // Eviction attempts within the window
+	pods             int32                    // Distinct pods evicted within the window
+	podKeys          map[string]struct{}      // Pods evicted within the window, by UID, or name for records without one
+	latest           *myappsv1.EvictionRecord // Latest eviction within the window
+	lastEvictionTime *metav1.Time             // Latest eviction, within the window or not
+}
+
+// aggregateEvictions summarises the records of a PDBWatcher at now
+func aggregateEvictions(pdbWatcher *myappsv1.PDBWatcher, records []myappsv1.EvictionRecord, now time.Time) windowEvictions {
+	var evictions windowEvictions
+	pods := make(map[string]struct{})
+	for i, record := range records {
+		evictionTime := record.Spec.EvictionTime
+		if now.Sub(evictionTime.Time) < evictionWindow(pdbWatcher) {
+			evictions.attempts += max(record.Spec.Attempts, 1)
+			pod := string(record.Spec.PodUID)
+			if pod == "" {
+				pod = record.Spec.PodName
+			}
+			pods[pod] = struct{}{}
+			if evictions.latest == nil || evictionTime.After(evictions.latest.Spec.EvictionTime.Time) {
+				evictions.latest = &records[i]
+			}
+		}
+		if evictions.lastEvictionTime == nil || evictionTime.After(evictions.lastEvictionTime.Time) {
+			evictions.lastEvictionTime = &evictionTime
+		}
+	}
+	evictions.pods = int32(len(pods))
+	evictions.podKeys = pods
+	return evictions
+}
+
+// pending returns the pods evicted within the window that are still running, the
+// evictions of the others already completed
+func (e windowEvictions) pending(pods []corev1.Pod) int32 {
+	var pending int32
+	for _, pod := range livePods(pods) {
+		_, byUID := e.podKeys[string(pod.UID)]
+		_, byName := e.podKeys[pod.Name]
+		if byUID || byName {
+			pending++
+		}
+	}
+	return pending
+}
+
+// count returns the evictions within the window as counted by the trigger policy
+func (e windowEvictions) count(trigger *myappsv1.TriggerPolicy) int32 {
+	if trigger != nil && trigger.CountBy == myappsv1.CountByDistinctPods {
+		return e.pods
+	}
+	return e.attempts
+}
+
+// triggerMinEvictions returns the number of evictions within the window needed to surge
+func triggerMinEvictions(trigger *myappsv1.TriggerPolicy) int32 {
+	if trigger == nil || trigger.MinEvictions < 1 {
+		return 1
+	}
+	return trigger.MinEvictions
+}
+
+// cooldownEnd returns when a new surge may start after the last revert, a surge in progress is never cooling down
+func cooldownEnd(pdbWatcher *myappsv1.PDBWatcher) time.Time {
+	stabilization := pdbWatcher.Spec.Stabilization
+	if stabilization == nil || stabilization.Cooldown == nil || pdbWatcher.Status.LastRevertTime == nil || pdbWatcher.Status.SurgeStartTime != nil {
+		return time.Time{}
+	}
+	return pdbWatcher.Status.LastRevertTime.Add(stabilization.Cooldown.Duration)
+}
+
+// revertTime returns when a surge may be reverted, once it was held for MinSurgeDuration
+// and the PDB allowed disruptions for DisruptionsAllowedWindow
+func revertTime(pdbWatcher *myappsv1.PDBWatcher) time.Time {
+	stabilization := pdbWatcher.Spec.Stabilization
+	if stabilization == nil {
+		return time.Time{}
+	}
+	var revert time.Time
+	if stabilization.MinSurgeDuration != nil && pdbWatcher.Status.SurgeStartTime != nil {
+		revert = pdbWatcher.Status.SurgeStartTime.Add(stabilization.MinSurgeDuration.Duration)
+	}
+	if stabilization.DisruptionsAllowedWindow != nil && pdbWatcher.Status.DisruptionsAllowedSince != nil {
+		if stable := pdbWatcher.Status.DisruptionsAllowedSince.Add(stabilization.DisruptionsAllowedWindow.Duration); stable.After(revert) {
+			revert = stable
+		}
+	}
+	return revert
+}
+
+// surgeRequeue returns how long until a surged PDBWatcher's stabilization is over or its latest
+// eviction leaves the window, whichever comes first, 0 without a surge or anything to wait for
+func surgeRequeue(pdbWatcher *myappsv1.PDBWatcher, latest *myappsv1.EvictionRecord, now time.Time) time.Duration {
+	if pdbWatcher.Status.SurgeStartTime == nil {
+		return 0
+	}
+	var requeue time.Duration
+	if revertTime := revertTime(pdbWatcher); now.Before(revertTime) {
+		requeue = revertTime.Sub(now)
+	}
+	if latest != nil {
+		if expires := latest.Spec.EvictionTime.Add(evictionWindow(pdbWatcher)); now.Before(expires) && (requeue == 0 || expires.Sub(now) < requeue) {
+			requeue = expires.Sub(now)
+		}
+	}
+	return requeue
+}
+
+// drainPollInterval is how often a surge waiting for a drain to complete checks the nodes
+const drainPollInterval = 30 * time.Second
+
+// nodeCordoned reports whether the node was cordoned, e.g. by kubectl drain
+func nodeCordoned(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return true
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == corev1.TaintNodeUnschedulable {
+			return true
+		}
+	}
+	return false
+}
+
+// scaleDownPollInterval is how often a stepwise scale-down checks whether the PDB settled
+const scaleDownPollInterval = 10 * time.Second
+
+// scaleDownHold returns how long a stepwise scale-down is held because of the latest
+// eviction within the window, once it was requested after the scale-down started. It is
+// 0 if the scale-down may continue.
+func scaleDownHold(pdbWatcher *myappsv1.PDBWatcher, latest *myappsv1.EvictionRecord, now time.Time) time.Duration {
+	start := pdbWatcher.Status.ScaleDownStartTime
+	if start == nil || latest == nil || !latest.Spec.EvictionTime.After(start.Time) {
+		return 0
+	}
+	return max(latest.Spec.EvictionTime.Add(evictionWindow(pdbWatcher)).Sub(now), 0)
+}
+
+// pdbSettled reports whether the PDB's status is current and all the pods it needs are
+// healthy, so the next step of a scale-down can't dip below the budget
+func pdbSettled(pdb *policyv1.PodDisruptionBudget) bool {
+	return pdb.Status.ObservedGeneration >= pdb.Generation &&
+		pdb.Status.CurrentHealthy >= pdb.Status.DesiredHealthy
+}
+
+// scaleDownStep returns the replicas after the next step from replicas towards the
+// baseline, removing no more replicas than the PDB allows to be disrupted
+func scaleDownStep(policy *myappsv1.ScaleDownPolicy, replicas, baseline, disruptionsAllowed int32) int32 {
+	step := max(policy.Step, 1)
+	step = min(step, disruptionsAllowed)
+	return max(replicas-step, baseline)
+}
+
+// evictionWindow returns how long an eviction counts towards a scale up
+func evictionWindow(pdbWatcher *myappsv1.PDBWatcher) time.Duration {
+	if pdbWatcher.Spec.EvictionWindow != nil {
+		return pdbWatcher.Spec.EvictionWindow.Duration
+	}
+	return myappsv1.DefaultEvictionWindow
+}
+
+// surgeReplicas resolves a maxSurge value against the replica baseline, defaulting to 1.
+// Percentages round up like a Deployment's maxSurge, so a small workload can still surge.
+func surgeReplicas(maxSurge *intstr.IntOrString, replicas int32) int32 {
+	if maxSurge == nil {
+		return 1 // Default max surge value
+	}
+	surge, err := intstr.GetScaledValueFromIntOrPercent(maxSurge, int(replicas), true)
+	if err != nil {
+		return 1
+	}
+	return int32(surge)
+}
+
 func (r *PDBWatcherReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&myappsv1.PDBWatcher{}).
+		Owns(&myappsv1.EvictionRecord{}).
+		Watches(&myappsv1.PDBWatcher{}, handler.EnqueueRequestsFromMapFunc(r.peerRequests)).
+		Watches(&policyv1.PodDisruptionBudget{}, handler.EnqueueRequestsFromMapFunc(r.pdbRequests)).
 		Complete(r)
 }
+
+// pdbRequests enqueues the PDBWatchers of a changed PDB, so a surge is reverted as soon as it allows disruptions
+func (r *PDBWatcherReconciler) pdbRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	watchers := &myappsv1.PDBWatcherList{}
+	if err := r.List(ctx, watchers, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list PDBWatchers for PDB", "pdb", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, pdbWatcher := range watchers.Items {
+		if pdbWatcher.Spec.PDBName == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&pdbWatcher)})
+		}
+	}
+	return requests
+}
@@ -6,10 +6,14 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/events"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -18,6 +22,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	v1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+	"github.com/Javier090/k8s-pdb-autoscaler/internal/metrics"
 )
 
 var _ = Describe("PDBWatcher Controller", func() {
@@ -114,8 +119,9 @@ var _ = Describe("PDBWatcher Controller", func() {
 		It("should successfully reconcile the resource", func() {
 			By("reconciling the created resource")
 			controllerReconciler := &PDBWatcherReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: events.NewFakeRecorder(10),
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -140,3 +146,222 @@ var _ = Describe("PDBWatcher Controller", func() {
 func int32Ptr(i int32) *int32 {
 	return &i
 }
+
+var _ = Describe("Eviction aggregation", func() {
+	Context("When counting evictions within the window", func() {
+		now := time.Now()
+		pdbWatcher := &v1.PDBWatcher{Spec: v1.PDBWatcherSpec{EvictionWindow: &metav1.Duration{Duration: 5 * time.Minute}}}
+		record := func(pod string, attempts int32, age time.Duration) v1.EvictionRecord {
+			return v1.EvictionRecord{Spec: v1.EvictionRecordSpec{EvictionLog: v1.EvictionLog{
+				PodName:      pod,
+				EvictionTime: metav1.NewTime(now.Add(-age)),
+				Attempts:     attempts,
+			}}}
+		}
+		records := []v1.EvictionRecord{
+			record("pod-a", 3, time.Minute),
+			record("pod-b", 0, 2*time.Minute),
+			record("pod-a", 1, 10*time.Minute), // Outside the window
+		}
+
+		It("should count attempts or distinct pods by the trigger policy", func() {
+			evictions := aggregateEvictions(pdbWatcher, records, now)
+			Expect(evictions.count(nil)).To(Equal(int32(4)))
+			Expect(evictions.count(&v1.TriggerPolicy{CountBy: v1.CountByDistinctPods})).To(Equal(int32(2)))
+			Expect(evictions.latest.Spec.PodName).To(Equal("pod-a"))
+			Expect(evictions.lastEvictionTime.Time).To(BeTemporally("==", now.Add(-time.Minute)))
+		})
+
+		It("should only count the evictions of pods still running as pending", func() {
+			evictions := aggregateEvictions(pdbWatcher, records, now)
+			terminating := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-b", DeletionTimestamp: &metav1.Time{Time: now}}}
+			pods := []corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Name: "pod-a"}}, terminating, {ObjectMeta: metav1.ObjectMeta{Name: "pod-d"}}}
+			Expect(evictions.pending(pods)).To(Equal(int32(1)))
+		})
+
+		It("should need one eviction unless the trigger policy says otherwise", func() {
+			Expect(triggerMinEvictions(nil)).To(Equal(int32(1)))
+			Expect(triggerMinEvictions(&v1.TriggerPolicy{MinEvictions: 3})).To(Equal(int32(3)))
+		})
+	})
+})
+
+var _ = Describe("Surge stabilization", func() {
+	Context("When a PDBWatcher has a stabilization policy", func() {
+		now := time.Now()
+		minute := &metav1.Duration{Duration: time.Minute}
+		at := func(offset time.Duration) *metav1.Time {
+			return &metav1.Time{Time: now.Add(offset)}
+		}
+
+		It("should revert once the surge was held and the PDB allowed disruptions long enough", func() {
+			pdbWatcher := &v1.PDBWatcher{
+				Spec: v1.PDBWatcherSpec{Stabilization: &v1.StabilizationPolicy{
+					MinSurgeDuration:         &metav1.Duration{Duration: 5 * time.Minute},
+					DisruptionsAllowedWindow: minute,
+				}},
+				Status: v1.PDBWatcherStatus{SurgeStartTime: at(-2 * time.Minute), DisruptionsAllowedSince: at(0)},
+			}
+			Expect(revertTime(pdbWatcher)).To(BeTemporally("==", now.Add(3*time.Minute)))
+
+			pdbWatcher.Status.DisruptionsAllowedSince = at(4 * time.Minute)
+			Expect(revertTime(pdbWatcher)).To(BeTemporally("==", now.Add(5*time.Minute)))
+
+			pdbWatcher.Spec.Stabilization = nil
+			Expect(revertTime(pdbWatcher).IsZero()).To(BeTrue())
+		})
+
+		It("should cool down after a revert until a new surge starts", func() {
+			pdbWatcher := &v1.PDBWatcher{
+				Spec:   v1.PDBWatcherSpec{Stabilization: &v1.StabilizationPolicy{Cooldown: minute}},
+				Status: v1.PDBWatcherStatus{LastRevertTime: at(-30 * time.Second)},
+			}
+			Expect(cooldownEnd(pdbWatcher)).To(BeTemporally("==", now.Add(30*time.Second)))
+
+			pdbWatcher.Status.SurgeStartTime = at(0)
+			Expect(cooldownEnd(pdbWatcher).IsZero()).To(BeTrue())
+		})
+
+		It("should check back on a surge once it is stable or its latest eviction leaves the window", func() {
+			pdbWatcher := &v1.PDBWatcher{
+				Spec: v1.PDBWatcherSpec{
+					EvictionWindow: &metav1.Duration{Duration: 5 * time.Minute},
+					Stabilization:  &v1.StabilizationPolicy{MinSurgeDuration: &metav1.Duration{Duration: 10 * time.Minute}},
+				},
+				Status: v1.PDBWatcherStatus{SurgeStartTime: at(-time.Minute)},
+			}
+			latest := &v1.EvictionRecord{Spec: v1.EvictionRecordSpec{EvictionLog: v1.EvictionLog{EvictionTime: *at(-2 * time.Minute)}}}
+			Expect(surgeRequeue(pdbWatcher, latest, now)).To(Equal(3 * time.Minute))
+			Expect(surgeRequeue(pdbWatcher, nil, now)).To(Equal(9 * time.Minute))
+
+			pdbWatcher.Status.SurgeStartTime = nil
+			Expect(surgeRequeue(pdbWatcher, latest, now)).To(BeZero())
+		})
+	})
+})
+
+var _ = Describe("Stepwise scale-down", func() {
+	Context("When reverting a surge in steps", func() {
+		It("should remove a step at a time, within the PDB's disruptions and down to the baseline", func() {
+			Expect(scaleDownStep(&v1.ScaleDownPolicy{}, 8, 4, 3)).To(Equal(int32(7)))
+			Expect(scaleDownStep(&v1.ScaleDownPolicy{Step: 2}, 8, 4, 3)).To(Equal(int32(6)))
+			Expect(scaleDownStep(&v1.ScaleDownPolicy{Step: 3}, 8, 4, 1)).To(Equal(int32(7)))
+			Expect(scaleDownStep(&v1.ScaleDownPolicy{Step: 3}, 5, 4, 3)).To(Equal(int32(4)))
+		})
+
+		It("should wait until the PDB's status is current and healthy", func() {
+			pdb := &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Status: policyv1.PodDisruptionBudgetStatus{
+					ObservedGeneration: 2,
+					ExpectedPods:       8, // Pods of the previous step still terminating
+					CurrentHealthy:     6,
+					DesiredHealthy:     6,
+				},
+			}
+			Expect(pdbSettled(pdb)).To(BeTrue())
+
+			pdb.Status.CurrentHealthy = 5
+			Expect(pdbSettled(pdb)).To(BeFalse())
+
+			pdb.Status.CurrentHealthy = 6
+			pdb.Generation = 3
+			Expect(pdbSettled(pdb)).To(BeFalse())
+		})
+
+		It("should hold while an eviction requested since the scale-down started is within the window", func() {
+			now := time.Now()
+			pdbWatcher := &v1.PDBWatcher{
+				Spec:   v1.PDBWatcherSpec{EvictionWindow: &metav1.Duration{Duration: 5 * time.Minute}},
+				Status: v1.PDBWatcherStatus{ScaleDownStartTime: &metav1.Time{Time: now.Add(-time.Minute)}},
+			}
+			eviction := func(age time.Duration) *v1.EvictionRecord {
+				return &v1.EvictionRecord{Spec: v1.EvictionRecordSpec{EvictionLog: v1.EvictionLog{EvictionTime: metav1.NewTime(now.Add(-age))}}}
+			}
+
+			Expect(scaleDownHold(pdbWatcher, eviction(30*time.Second), now)).To(Equal(4*time.Minute + 30*time.Second))
+			Expect(scaleDownHold(pdbWatcher, eviction(2*time.Minute), now)).To(BeZero())
+			Expect(scaleDownHold(pdbWatcher, nil, now)).To(BeZero())
+		})
+	})
+})
+
+var _ = Describe("Drain completion", func() {
+	Context("When a PDBWatcher reverts once the drain is complete", func() {
+		It("should count the live pods on cordoned nodes", func() {
+			cordoned := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cordoned"}, Spec: corev1.NodeSpec{Unschedulable: true}}
+			tainted := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: "tainted"},
+				Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: corev1.TaintNodeUnschedulable, Effect: corev1.TaintEffectNoSchedule}}},
+			}
+			ready := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "ready"}}
+			reconciler := &PDBWatcherReconciler{Client: fake.NewClientBuilder().WithObjects(cordoned, tainted, ready).Build()}
+
+			pod := func(node string, terminating bool) corev1.Pod {
+				pod := corev1.Pod{Spec: corev1.PodSpec{NodeName: node}}
+				if terminating {
+					pod.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+				}
+				return pod
+			}
+			pods := []corev1.Pod{
+				pod("cordoned", false),
+				pod("cordoned", true), // Already evicted
+				pod("tainted", false),
+				pod("ready", false),
+				pod("deleted", false), // Node is gone
+				pod("", false),        // Not scheduled
+			}
+
+			draining, err := reconciler.podsOnCordonedNodes(context.Background(), pods)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(draining).To(Equal(2))
+		})
+	})
+})
+
+var _ = Describe("PDBWatcher metrics", func() {
+	Context("When a PDBWatcher is deleted", func() {
+		It("should delete its series", func() {
+			scheme := runtime.NewScheme()
+			Expect(v1.AddToScheme(scheme)).To(Succeed())
+			reconciler := &PDBWatcherReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+			started := testutil.CollectAndCount(metrics.SurgesStarted)
+			completed := testutil.CollectAndCount(metrics.SurgesCompleted)
+			surged := testutil.CollectAndCount(metrics.SurgedReplicas)
+			metrics.SurgesStarted.WithLabelValues("default", "deleted-watcher").Inc()
+			metrics.SurgesCompleted.WithLabelValues("default", "deleted-watcher").Inc()
+			metrics.SurgedReplicas.WithLabelValues("default", "deleted-watcher").Set(2)
+
+			_, err := reconciler.Reconcile(context.Background(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: "default", Name: "deleted-watcher"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(testutil.CollectAndCount(metrics.SurgesStarted)).To(Equal(started))
+			Expect(testutil.CollectAndCount(metrics.SurgesCompleted)).To(Equal(completed))
+			Expect(testutil.CollectAndCount(metrics.SurgedReplicas)).To(Equal(surged))
+		})
+	})
+})
+
+var _ = Describe("PDB changes", func() {
+	It("should enqueue the PDBWatchers of a changed PDB", func() {
+		scheme := runtime.NewScheme()
+		Expect(v1.AddToScheme(scheme)).To(Succeed())
+		watcher := func(name, pdb string) *v1.PDBWatcher {
+			return &v1.PDBWatcher{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+				Spec:       v1.PDBWatcherSpec{PDBName: pdb},
+			}
+		}
+		reconciler := &PDBWatcherReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			watcher("example-pdbwatcher", "example-pdb"),
+			watcher("other-pdbwatcher", "other-pdb"),
+		).Build()}
+		pdb := &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "example-pdb", Namespace: "default"}}
+
+		Expect(reconciler.pdbRequests(context.Background(), pdb)).To(Equal([]reconcile.Request{
+			{NamespacedName: types.NamespacedName{Namespace: "default", Name: "example-pdbwatcher"}},
+		}))
+	})
+})
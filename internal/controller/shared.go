@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+// watcherTarget returns the workload a PDBWatcher scales, from its spec or, when its
+// PDB's pods determine it, from its status
+func watcherTarget(pdbWatcher *myappsv1.PDBWatcher) workloadKey {
+	if pdbWatcher.Spec.DeploymentName != "" {
+		kind := pdbWatcher.Spec.TargetKind
+		if kind == "" {
+			kind = myappsv1.DeploymentKind
+		}
+		return workloadKey{kind: kind, name: pdbWatcher.Spec.DeploymentName}
+	}
+	kind, name, _ := strings.Cut(pdbWatcher.Status.Workload, "/")
+	return workloadKey{kind: kind, name: name}
+}
+
+// sharedWatchers returns the other PDBWatchers of watchers that scale target
+func sharedWatchers(pdbWatcher *myappsv1.PDBWatcher, target workloadKey, watchers []myappsv1.PDBWatcher) []*myappsv1.PDBWatcher {
+	var peers []*myappsv1.PDBWatcher
+	for i := range watchers {
+		peer := &watchers[i]
+		if peer.Name != pdbWatcher.Name && peer.DeletionTimestamp == nil && watcherTarget(peer) == target {
+			peers = append(peers, peer)
+		}
+	}
+	return peers
+}
+
+// baselineOwner elects the PDBWatcher owning the baseline of a shared workload, the oldest
+// one, so the election is stable while PDBWatchers come and go
+func baselineOwner(pdbWatcher *myappsv1.PDBWatcher, peers []*myappsv1.PDBWatcher) *myappsv1.PDBWatcher {
+	candidates := append([]*myappsv1.PDBWatcher{pdbWatcher}, peers...)
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		return a.Name < b.Name
+	})
+	return candidates[0]
+}
+
+// keepsBaseline reports whether a PDBWatcher that just became the baseline owner of a shared
+// workload keeps the baseline of previousOwner, as the workload still has the replicas it
+// last set. Otherwise the workload was scaled by someone else since, and its replicas are
+// adopted as the baseline.
+func keepsBaseline(pdbWatcher *myappsv1.PDBWatcher, previousOwner string, replicas int32) bool {
+	return previousOwner != "" && previousOwner != pdbWatcher.Name &&
+		pdbWatcher.Status.ResourceVersion != "" && replicas == pdbWatcher.Status.ScaledReplicas
+}
+
+// aggregateSurge combines the surge the owner needs with the surges its peers request
+func aggregateSurge(aggregation myappsv1.SurgeAggregation, own int32, peers []*myappsv1.PDBWatcher) int32 {
+	surge := own
+	for _, peer := range peers {
+		if aggregation == myappsv1.SurgeAggregationSum {
+			surge += peer.Status.DesiredSurge
+		} else {
+			surge = max(surge, peer.Status.DesiredSurge)
+		}
+	}
+	return surge
+}
+
+// surgeReleased reports whether the workload may be reverted for a PDBWatcher. A follower
+// releases its surge once its PDB allows disruptions again. The baseline owner reverts once
+// the surge aggregated over it and its peers is 0, whatever its own PDB allows, as it may have
+// surged only for its peers.
+func surgeReleased(pdbWatcher, owner *myappsv1.PDBWatcher, pdb *policyv1.PodDisruptionBudget, peers []*myappsv1.PDBWatcher) bool {
+	if owner != pdbWatcher {
+		return pdb.Status.DisruptionsAllowed > 0
+	}
+	own := pdbWatcher.Status.DesiredSurge
+	if pdb.Status.DisruptionsAllowed > 0 {
+		own = 0 // Its own PDB needs no surge anymore
+	}
+	return aggregateSurge(pdbWatcher.Spec.SurgeAggregation, own, peers) == 0
+}
+
+// requestSurge records the surge a PDBWatcher needs from the baseline owner of its shared
+// workload, which scales the workload on its behalf
+func (r *PDBWatcherReconciler) requestSurge(ctx context.Context, pdbWatcher, owner *myappsv1.PDBWatcher, target *workload, requested int32) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	// The surge start bounds how long SurgeFirst evictions are held for the request
+	if pdbWatcher.Status.DesiredSurge == 0 {
+		pdbWatcher.Status.SurgeStartTime = nil
+	} else if pdbWatcher.Status.SurgeStartTime == nil {
+		now := metav1.Now()
+		pdbWatcher.Status.SurgeStartTime = &now
+	}
+
+	err := r.Status().Update(ctx, pdbWatcher)
+	if err != nil {
+		logger.Error(err, "Failed to update PDBWatcher status")
+		return ctrl.Result{}, reconcileError("UpdateStatus", err)
+	}
+	if pdbWatcher.Status.DesiredSurge != requested {
+		logger.Info(fmt.Sprintf("Requesting a surge of %d replicas of %s from %s", pdbWatcher.Status.DesiredSurge, target, owner.Name))
+		if pdbWatcher.Status.DesiredSurge > 0 {
+			r.event(pdbWatcher, target, corev1.EventTypeNormal, "SurgeRequested", "ScaleUp",
+				"Requested a surge of %d replicas of %s from PDBWatcher %s, which owns its baseline",
+				pdbWatcher.Status.DesiredSurge, target, owner.Name)
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// peerRequests enqueues the PDBWatchers sharing a workload with a changed PDBWatcher, so
+// the baseline owner acts on the surges its peers request
+func (r *PDBWatcherReconciler) peerRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	pdbWatcher, ok := obj.(*myappsv1.PDBWatcher)
+	if !ok {
+		return nil
+	}
+	target := watcherTarget(pdbWatcher)
+	if target.name == "" {
+		return nil
+	}
+
+	watchers := &myappsv1.PDBWatcherList{}
+	if err := r.List(ctx, watchers, client.InNamespace(pdbWatcher.Namespace)); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list PDBWatchers sharing a workload")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, peer := range sharedWatchers(pdbWatcher, target, watchers.Items) {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(peer)})
+	}
+	return requests
+}
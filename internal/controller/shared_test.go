@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+var _ = Describe("Shared workloads", func() {
+	Context("When several PDBWatchers scale one workload", func() {
+		created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		watcher := func(name, deployment string, age time.Duration, desiredSurge int32) *v1.PDBWatcher {
+			return &v1.PDBWatcher{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", CreationTimestamp: metav1.NewTime(created.Add(-age))},
+				Spec:       v1.PDBWatcherSpec{PDBName: name + "-pdb", DeploymentName: deployment},
+				Status:     v1.PDBWatcherStatus{DesiredSurge: desiredSurge},
+			}
+		}
+		target := workloadKey{kind: v1.DeploymentKind, name: "example-deployment"}
+
+		It("should find the PDBWatchers sharing the target", func() {
+			zoneA := watcher("zone-a", "example-deployment", time.Hour, 0)
+			discovered := watcher("zone-b", "", 0, 0)
+			discovered.Status.Workload = target.String()
+			watchers := []v1.PDBWatcher{*zoneA, *discovered, *watcher("other", "other-deployment", 0, 0)}
+
+			peers := sharedWatchers(zoneA, target, watchers)
+			Expect(peers).To(HaveLen(1))
+			Expect(peers[0].Name).To(Equal("zone-b"))
+			Expect(watcherTarget(discovered)).To(Equal(target))
+		})
+
+		It("should elect the oldest PDBWatcher as the baseline owner", func() {
+			zoneA := watcher("zone-a", "example-deployment", time.Minute, 0)
+			zoneB := watcher("zone-b", "example-deployment", time.Hour, 0)
+			zoneC := watcher("zone-c", "example-deployment", time.Hour, 0)
+
+			Expect(baselineOwner(zoneA, []*v1.PDBWatcher{zoneC, zoneB}).Name).To(Equal("zone-b"))
+			Expect(baselineOwner(zoneC, []*v1.PDBWatcher{zoneA, zoneB}).Name).To(Equal("zone-b"))
+		})
+
+		It("should keep the baseline of the previous owner unless the workload was scaled since", func() {
+			successor := watcher("zone-b", "example-deployment", 0, 0)
+			successor.Status.ResourceVersion = "41"
+			successor.Status.MinReplicas = 3
+			successor.Status.ScaledReplicas = 5
+
+			Expect(keepsBaseline(successor, "zone-a", 5)).To(BeTrue())
+			Expect(keepsBaseline(successor, "zone-a", 7)).To(BeFalse())
+			Expect(keepsBaseline(successor, "", 5)).To(BeFalse())
+			Expect(keepsBaseline(successor, "zone-b", 5)).To(BeFalse())
+		})
+
+		It("should aggregate the surges requested by the peers", func() {
+			peers := []*v1.PDBWatcher{
+				watcher("zone-b", "example-deployment", 0, 2),
+				watcher("zone-c", "example-deployment", 0, 3),
+			}
+
+			Expect(aggregateSurge(v1.SurgeAggregationMax, 1, peers)).To(Equal(int32(3)))
+			Expect(aggregateSurge("", 1, peers)).To(Equal(int32(3)))
+			Expect(aggregateSurge(v1.SurgeAggregationSum, 1, peers)).To(Equal(int32(6)))
+			Expect(aggregateSurge(v1.SurgeAggregationSum, 0, nil)).To(BeZero())
+		})
+
+		It("should revert for the owner once neither it nor its peers need a surge, whatever its own PDB allows", func() {
+			owner := watcher("zone-a", "example-deployment", time.Hour, 0)
+			follower := watcher("zone-b", "example-deployment", 0, 0)
+			atBudget := &policyv1.PodDisruptionBudget{}
+			allowing := &policyv1.PodDisruptionBudget{Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1}}
+
+			By("holding the surge while a peer requests one")
+			follower.Status.DesiredSurge = 2
+			Expect(surgeReleased(owner, owner, atBudget, []*v1.PDBWatcher{follower})).To(BeFalse())
+			Expect(surgeReleased(owner, owner, allowing, []*v1.PDBWatcher{follower})).To(BeFalse())
+
+			By("reverting at budget once the peers cleared their requests")
+			follower.Status.DesiredSurge = 0
+			Expect(surgeReleased(owner, owner, atBudget, []*v1.PDBWatcher{follower})).To(BeTrue())
+
+			By("holding the surge the owner needs for its own PDB")
+			owner.Status.DesiredSurge = 1
+			Expect(surgeReleased(owner, owner, atBudget, []*v1.PDBWatcher{follower})).To(BeFalse())
+			Expect(surgeReleased(owner, owner, allowing, []*v1.PDBWatcher{follower})).To(BeTrue())
+
+			By("releasing a follower's request once its own PDB allows disruptions")
+			Expect(surgeReleased(follower, owner, atBudget, []*v1.PDBWatcher{owner})).To(BeFalse())
+			Expect(surgeReleased(follower, owner, allowing, []*v1.PDBWatcher{owner})).To(BeTrue())
+		})
+
+		It("should enqueue the peers of a changed PDBWatcher", func() {
+			scheme := runtime.NewScheme()
+			Expect(v1.AddToScheme(scheme)).To(Succeed())
+			zoneA := watcher("zone-a", "example-deployment", 0, 0)
+			reconciler := &PDBWatcherReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				zoneA,
+				watcher("zone-b", "example-deployment", 0, 0),
+				watcher("other", "other-deployment", 0, 0),
+			).Build()}
+
+			Expect(reconciler.peerRequests(context.Background(), zoneA)).To(Equal([]reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: "default", Name: "zone-b"}},
+			}))
+		})
+	})
+})
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"fmt"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+// disruptionsAllowed computes the PDB's DisruptionsAllowed like the disruption controller
+// does, for expected pods of which healthy are healthy. It returns false if the PDB sets
+// neither minAvailable nor maxUnavailable, or sets an invalid percentage.
+func disruptionsAllowed(pdb *policyv1.PodDisruptionBudget, expected, healthy int32) (int32, bool) {
+	var desiredHealthy int32
+	switch {
+	case pdb.Spec.MaxUnavailable != nil:
+		maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MaxUnavailable, int(expected), true)
+		if err != nil {
+			return 0, false
+		}
+		desiredHealthy = max(expected-int32(maxUnavailable), 0)
+	case pdb.Spec.MinAvailable != nil:
+		minAvailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MinAvailable, int(expected), true)
+		if err != nil {
+			return 0, false
+		}
+		desiredHealthy = int32(minAvailable)
+	default:
+		return 0, false
+	}
+	return max(healthy-desiredHealthy, 0), true
+}
+
+// requiredSurge returns the fewest replicas to add to the baseline for the PDB to allow
+// pending disruptions, once the added replicas are healthy. Unhealthy pods are assumed to
+// be the surged replicas still starting, so a surge doesn't grow while they become ready. It
+// returns maxSurge and false if no surge up to maxSurge is enough, e.g. with an integer
+// maxUnavailable, where healthy replicas never add disruptions.
+func requiredSurge(pdb *policyv1.PodDisruptionBudget, baseline, replicas, pending, maxSurge int32) (int32, bool) {
+	surged := max(replicas-baseline, 0)
+	expected := max(pdb.Status.ExpectedPods-surged, 0)
+	healthy := min(pdb.Status.CurrentHealthy, expected)
+	for surge := int32(1); surge <= maxSurge; surge++ {
+		allowed, ok := disruptionsAllowed(pdb, expected+surge, healthy+surge)
+		if !ok {
+			break
+		}
+		if allowed >= pending {
+			return surge, true
+		}
+	}
+	return maxSurge, false
+}
+
+// surgeEnabledCondition returns the SurgeEnabled condition, False when the PDB was opted
+// out, allows no pod to be unavailable, or the workload's maxSurge is explicitly 0
+func surgeEnabledCondition(pdb *policyv1.PodDisruptionBudget, maxSurge *intstr.IntOrString, generation int64) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               myappsv1.ConditionSurgeEnabled,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+	}
+	switch {
+	case pdb.Annotations[myappsv1.SurgeDisabledAnnotation] == "true":
+		condition.Reason = myappsv1.SurgeDisabledByAnnotation
+		condition.Message = fmt.Sprintf("PDB %s is annotated with %s", pdb.Name, myappsv1.SurgeDisabledAnnotation)
+	case isZero(pdb.Spec.MaxUnavailable):
+		condition.Reason = myappsv1.SurgeDisabledMaxUnavailable
+		condition.Message = fmt.Sprintf("PDB %s has maxUnavailable %s, extra replicas never allow a disruption", pdb.Name, pdb.Spec.MaxUnavailable)
+	case isZero(maxSurge):
+		condition.Reason = myappsv1.SurgeDisabledMaxSurge
+		condition.Message = fmt.Sprintf("maxSurge is %s, the workload may not run extra replicas", maxSurge)
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = myappsv1.SurgeEnabledReason
+		condition.Message = fmt.Sprintf("Evictions blocked by PDB %s are answered with a surge", pdb.Name)
+	}
+	return condition
+}
+
+// isZero reports whether value is set to 0 or 0%
+func isZero(value *intstr.IntOrString) bool {
+	if value == nil {
+		return false
+	}
+	scaled, err := intstr.GetScaledValueFromIntOrPercent(value, 100, true)
+	return err == nil && scaled == 0
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	v1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+var _ = Describe("Surge sizing", func() {
+	Context("When sizing a surge from the PDB", func() {
+		pdb := func(minAvailable, maxUnavailable *intstr.IntOrString, expected, healthy int32) *policyv1.PodDisruptionBudget {
+			return &policyv1.PodDisruptionBudget{
+				Spec:   policyv1.PodDisruptionBudgetSpec{MinAvailable: minAvailable, MaxUnavailable: maxUnavailable},
+				Status: policyv1.PodDisruptionBudgetStatus{ExpectedPods: expected, CurrentHealthy: healthy},
+			}
+		}
+		value := func(v intstr.IntOrString) *intstr.IntOrString {
+			return &v
+		}
+		exactSurge := func(pdb *policyv1.PodDisruptionBudget, baseline, replicas, pending, maxSurge int32) int32 {
+			surge, exact := requiredSurge(pdb, baseline, replicas, pending, maxSurge)
+			Expect(exact).To(BeTrue())
+			return surge
+		}
+
+		It("should add the fewest replicas for the pending evictions with minAvailable", func() {
+			budget := pdb(value(intstr.FromInt32(3)), nil, 3, 3)
+			Expect(exactSurge(budget, 3, 3, 1, 5)).To(Equal(int32(1)))
+			Expect(exactSurge(budget, 3, 3, 2, 5)).To(Equal(int32(2)))
+
+			// 80% of 4 pods, a percentage grows with the surge
+			budget = pdb(value(intstr.FromString("80%")), nil, 4, 4)
+			Expect(exactSurge(budget, 4, 4, 1, 10)).To(Equal(int32(1)))
+			Expect(exactSurge(budget, 4, 4, 2, 10)).To(Equal(int32(6)))
+		})
+
+		It("should add the fewest replicas for the pending evictions with a maxUnavailable percentage", func() {
+			budget := pdb(nil, value(intstr.FromString("20%")), 4, 4)
+			Expect(exactSurge(budget, 4, 4, 1, 5)).To(Equal(int32(1)))
+		})
+
+		It("should not grow a surge while its replicas start", func() {
+			budget := pdb(value(intstr.FromInt32(3)), nil, 4, 3)
+			Expect(exactSurge(budget, 3, 4, 1, 5)).To(Equal(int32(1)))
+		})
+
+		It("should fall back to maxSurge when no surge is enough", func() {
+			surge, exact := requiredSurge(pdb(nil, value(intstr.FromInt32(0)), 3, 3), 3, 3, 1, 2)
+			Expect(surge).To(Equal(int32(2)))
+			Expect(exact).To(BeFalse())
+
+			surge, exact = requiredSurge(pdb(value(intstr.FromString("100%")), nil, 3, 3), 3, 3, 1, 2)
+			Expect(surge).To(Equal(int32(2)))
+			Expect(exact).To(BeFalse())
+		})
+
+		It("should round a maxSurge percentage up", func() {
+			Expect(surgeReplicas(value(intstr.FromString("25%")), 3)).To(Equal(int32(1)))
+			Expect(surgeReplicas(value(intstr.FromString("25%")), 10)).To(Equal(int32(3)))
+			Expect(surgeReplicas(value(intstr.FromString("0%")), 10)).To(BeZero())
+			Expect(surgeReplicas(value(intstr.FromInt32(2)), 10)).To(Equal(int32(2)))
+			Expect(surgeReplicas(nil, 10)).To(Equal(int32(1)))
+		})
+	})
+})
+
+var _ = Describe("Surge by design", func() {
+	Context("When the PDB or workload blocks evictions by design", func() {
+		pdb := func(annotations map[string]string, maxUnavailable *intstr.IntOrString) *policyv1.PodDisruptionBudget {
+			return &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "example-pdb", Annotations: annotations},
+				Spec:       policyv1.PodDisruptionBudgetSpec{MaxUnavailable: maxUnavailable},
+			}
+		}
+		zero := intstr.FromInt32(0)
+		zeroPercent := intstr.FromString("0%")
+		one := intstr.FromInt32(1)
+
+		It("should refuse to surge with a reason", func() {
+			condition := surgeEnabledCondition(pdb(map[string]string{v1.SurgeDisabledAnnotation: "true"}, nil), nil, 1)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(v1.SurgeDisabledByAnnotation))
+
+			condition = surgeEnabledCondition(pdb(nil, &zeroPercent), nil, 1)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(v1.SurgeDisabledMaxUnavailable))
+
+			condition = surgeEnabledCondition(pdb(nil, &one), &zero, 1)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(v1.SurgeDisabledMaxSurge))
+		})
+
+		It("should surge otherwise", func() {
+			condition := surgeEnabledCondition(pdb(nil, &one), &one, 1)
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.ObservedGeneration).To(Equal(int64(1)))
+		})
+	})
+})
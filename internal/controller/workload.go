@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+// workload wraps a Deployment or StatefulSet so the reconciler can scale either kind
+type workload struct {
+	client.Object
+	kind     string
+	replicas **int32
+	template *corev1.PodTemplateSpec
+	maxSurge *intstr.IntOrString // Rolling update maxSurge, nil for StatefulSets
+}
+
+// newWorkload returns an empty workload of the given kind, ready to be fetched into
+func newWorkload(kind string) (*workload, error) {
+	switch kind {
+	case "", myappsv1.DeploymentKind:
+		deployment := &appsv1.Deployment{}
+		w := &workload{Object: deployment, kind: myappsv1.DeploymentKind, replicas: &deployment.Spec.Replicas, template: &deployment.Spec.Template}
+		return w, nil
+	case myappsv1.StatefulSetKind:
+		statefulSet := &appsv1.StatefulSet{}
+		w := &workload{Object: statefulSet, kind: myappsv1.StatefulSetKind, replicas: &statefulSet.Spec.Replicas, template: &statefulSet.Spec.Template}
+		return w, nil
+	default:
+		return nil, fmt.Errorf("unsupported workload kind %q", kind)
+	}
+}
+
+// getWorkload fetches the workload of the given kind
+func getWorkload(ctx context.Context, c client.Client, kind string, key types.NamespacedName) (*workload, error) {
+	w, err := newWorkload(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Get(ctx, key, w.Object); err != nil {
+		return nil, err
+	}
+	if deployment, ok := w.Object.(*appsv1.Deployment); ok && deployment.Spec.Strategy.RollingUpdate != nil {
+		w.maxSurge = deployment.Spec.Strategy.RollingUpdate.MaxSurge
+	}
+	return w, nil
+}
+
+// Replicas returns the desired replica count, defaulting to 1 like the API server
+func (w *workload) Replicas() int32 {
+	if *w.replicas == nil {
+		return 1
+	}
+	return **w.replicas
+}
+
+// SetReplicas sets the desired replica count
+func (w *workload) SetReplicas(replicas int32) {
+	*w.replicas = &replicas
+}
+
+// String returns the workload as kind/namespace/name for logging
+func (w *workload) String() string {
+	return fmt.Sprintf("%s %s/%s", w.kind, w.GetNamespace(), w.GetName())
+}
@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+// managedByAnnotations is the ManagedByLabel value of PDBWatchers created from workload annotations
+const managedByAnnotations = "workload-annotations"
+
+// WorkloadReconciler manages an implicit PDBWatcher for every Deployment or
+// StatefulSet carrying the pdb-autoscaler/enabled annotation
+type WorkloadReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Kind   string // Deployment or StatefulSet
+}
+
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps.mydomain.com,resources=pdbwatchers,verbs=get;list;watch;create;update;patch;delete
+
+func (r *WorkloadReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	// Fetch the workload
+	target, err := getWorkload(ctx, r.Client, r.Kind, req.NamespacedName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil // Workload deleted, its PDBWatcher is garbage collected
+		}
+		return ctrl.Result{}, err // Error fetching workload
+	}
+
+	// Fetch the implicit PDBWatcher, if any
+	watcherKey := types.NamespacedName{Name: implicitWatcherName(r.Kind, target.GetName()), Namespace: target.GetNamespace()}
+	existing := &myappsv1.PDBWatcher{}
+	err = r.Get(ctx, watcherKey, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err // Error fetching PDBWatcher
+	}
+	found := err == nil
+	if found && !isImplicitWatcher(existing, target) {
+		logger.Info(fmt.Sprintf("PDBWatcher %s exists and is not managed by %s, skipping", watcherKey, target))
+		return ctrl.Result{}, nil
+	}
+
+	// Remove the implicit PDBWatcher once the workload opts out
+	if target.GetAnnotations()[myappsv1.EnabledAnnotation] != "true" {
+		if found {
+			logger.Info(fmt.Sprintf("%s opted out, deleting PDBWatcher %s", target, watcherKey))
+			return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, existing))
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Find the PDB whose selector matches the workload's pods
+	pdbList := &policyv1.PodDisruptionBudgetList{}
+	err = r.List(ctx, pdbList, &client.ListOptions{Namespace: target.GetNamespace()})
+	if err != nil {
+		return ctrl.Result{}, err // Error listing PDBs
+	}
+
+	var matches []string
+	for _, pdb := range pdbList.Items {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil {
+			logger.Error(err, fmt.Sprintf("Invalid selector on PDB %s", pdb.Name))
+			continue
+		}
+		if selector.Matches(labels.Set(target.template.Labels)) {
+			matches = append(matches, pdb.Name)
+		}
+	}
+
+	if len(matches) != 1 {
+		// Wait for a single PDB to cover the workload, PDB changes requeue it
+		logger.Info(fmt.Sprintf("Found %d PDBs for %s, expected exactly one: %v", len(matches), target, matches))
+		if found {
+			// Stop surging for a PDB that no longer is the workload's only one
+			logger.Info(fmt.Sprintf("Deleting PDBWatcher %s of PDB %s", watcherKey, existing.Spec.PDBName))
+			return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, existing))
+		}
+		return ctrl.Result{}, nil
+	}
+	pdbName := matches[0]
+
+	// Don't fight an explicit PDBWatcher over the same PDB
+	watcherList := &myappsv1.PDBWatcherList{}
+	err = r.List(ctx, watcherList, &client.ListOptions{Namespace: target.GetNamespace()})
+	if err != nil {
+		return ctrl.Result{}, err // Error listing PDBWatchers
+	}
+	for _, watcher := range watcherList.Items {
+		if watcher.Name != watcherKey.Name && watcher.Spec.PDBName == pdbName {
+			logger.Info(fmt.Sprintf("PDB %s is already watched by PDBWatcher %s, skipping %s", pdbName, watcher.Name, target))
+			return ctrl.Result{}, nil
+		}
+	}
+
+	// Create or update the implicit PDBWatcher from the annotations
+	pdbWatcher := &myappsv1.PDBWatcher{ObjectMeta: metav1.ObjectMeta{Name: watcherKey.Name, Namespace: watcherKey.Namespace}}
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, pdbWatcher, func() error {
+		if pdbWatcher.Labels == nil {
+			pdbWatcher.Labels = map[string]string{}
+		}
+		pdbWatcher.Labels[myappsv1.ManagedByLabel] = managedByAnnotations
+		pdbWatcher.Spec.PDBName = pdbName
+		pdbWatcher.Spec.DeploymentName = target.GetName()
+		pdbWatcher.Spec.TargetKind = r.Kind
+		pdbWatcher.Spec.MaxSurge = annotationMaxSurge(ctx, target)
+		pdbWatcher.Spec.EvictionWindow = annotationEvictionWindow(ctx, target)
+		pdbWatcher.Spec.EvictionMode = annotationEvictionMode(ctx, target)
+		return controllerutil.SetControllerReference(target.Object, pdbWatcher, r.Scheme)
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if result != controllerutil.OperationResultNone {
+		logger.Info(fmt.Sprintf("PDBWatcher %s %s for %s", watcherKey, result, target))
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// implicitWatcherName returns the name of the PDBWatcher managed for a workload
+func implicitWatcherName(kind, name string) string {
+	return strings.ToLower(kind) + "-" + name
+}
+
+// isImplicitWatcher reports whether the PDBWatcher was created for the workload from its annotations
+func isImplicitWatcher(pdbWatcher *myappsv1.PDBWatcher, target *workload) bool {
+	return pdbWatcher.Labels[myappsv1.ManagedByLabel] == managedByAnnotations && metav1.IsControlledBy(pdbWatcher, target.Object)
+}
+
+// annotationMaxSurge parses the max-surge annotation, ignoring invalid values
+func annotationMaxSurge(ctx context.Context, target *workload) *intstr.IntOrString {
+	value, ok := target.GetAnnotations()[myappsv1.MaxSurgeAnnotation]
+	if !ok {
+		return nil
+	}
+	maxSurge := intstr.Parse(value)
+	if maxSurge.Type == intstr.Int && maxSurge.IntVal < 0 || maxSurge.Type == intstr.String && !strings.HasSuffix(maxSurge.StrVal, "%") {
+		log.FromContext(ctx).Info(fmt.Sprintf("Ignoring invalid %s annotation %q on %s", myappsv1.MaxSurgeAnnotation, value, target))
+		return nil
+	}
+	return &maxSurge
+}
+
+// annotationEvictionWindow parses the eviction-window annotation, ignoring invalid values
+func annotationEvictionWindow(ctx context.Context, target *workload) *metav1.Duration {
+	value, ok := target.GetAnnotations()[myappsv1.EvictionWindowAnnotation]
+	if !ok {
+		return nil
+	}
+	window, err := time.ParseDuration(value)
+	if err != nil || window <= 0 {
+		log.FromContext(ctx).Info(fmt.Sprintf("Ignoring invalid %s annotation %q on %s", myappsv1.EvictionWindowAnnotation, value, target))
+		return nil
+	}
+	return &metav1.Duration{Duration: window}
+}
+
+// annotationEvictionMode parses the eviction-mode annotation, ignoring invalid values
+func annotationEvictionMode(ctx context.Context, target *workload) myappsv1.EvictionMode {
+	value, ok := target.GetAnnotations()[myappsv1.EvictionModeAnnotation]
+	if !ok {
+		return ""
+	}
+	mode := myappsv1.EvictionMode(value)
+	if mode != myappsv1.EvictionModeAllow && mode != myappsv1.EvictionModeSurgeFirst {
+		log.FromContext(ctx).Info(fmt.Sprintf("Ignoring invalid %s annotation %q on %s", myappsv1.EvictionModeAnnotation, value, target))
+		return ""
+	}
+	return mode
+}
+
+// workloadsForPDB requeues the opted-in workloads in a PDB's namespace when the PDB changes
+func (r *WorkloadReconciler) workloadsForPDB(ctx context.Context, obj client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+
+	var objects []client.Object
+	if r.Kind == myappsv1.StatefulSetKind {
+		statefulSetList := &appsv1.StatefulSetList{}
+		if err := r.List(ctx, statefulSetList, client.InNamespace(obj.GetNamespace())); err != nil {
+			logger.Error(err, "Failed to list StatefulSets for PDB", "pdb", obj.GetName())
+			return nil
+		}
+		for i := range statefulSetList.Items {
+			objects = append(objects, &statefulSetList.Items[i])
+		}
+	} else {
+		deploymentList := &appsv1.DeploymentList{}
+		if err := r.List(ctx, deploymentList, client.InNamespace(obj.GetNamespace())); err != nil {
+			logger.Error(err, "Failed to list Deployments for PDB", "pdb", obj.GetName())
+			return nil
+		}
+		for i := range deploymentList.Items {
+			objects = append(objects, &deploymentList.Items[i])
+		}
+	}
+
+	var requests []reconcile.Request
+	for _, object := range objects {
+		if _, ok := object.GetAnnotations()[myappsv1.EnabledAnnotation]; ok {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(object)})
+		}
+	}
+	return requests
+}
+
+// hasEnabledAnnotation filters workload events down to workloads that are, or were, opted in
+func hasEnabledAnnotation() predicate.Predicate {
+	annotated := func(obj client.Object) bool {
+		_, ok := obj.GetAnnotations()[myappsv1.EnabledAnnotation]
+		return ok
+	}
+	return predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return annotated(e.Object) },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return annotated(e.ObjectOld) || annotated(e.ObjectNew) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return annotated(e.Object) },
+	}
+}
+
+func (r *WorkloadReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	w, err := newWorkload(r.Kind)
+	if err != nil {
+		return err
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(strings.ToLower(r.Kind)+"-annotations").
+		For(w.Object, builder.WithPredicates(hasEnabledAnnotation())).
+		Owns(&myappsv1.PDBWatcher{}).
+		Watches(&policyv1.PodDisruptionBudget{}, handler.EnqueueRequestsFromMapFunc(r.workloadsForPDB)).
+		Complete(r)
+}
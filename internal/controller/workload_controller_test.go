@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+var _ = Describe("Workload Controller", func() {
+	Context("When reconciling an annotated Deployment", func() {
+		const deploymentName = "annotated-deployment"
+		const namespace = "default"
+
+		ctx := context.Background()
+		deploymentKey := types.NamespacedName{Name: deploymentName, Namespace: namespace}
+		watcherKey := types.NamespacedName{Name: "deployment-" + deploymentName, Namespace: namespace}
+
+		BeforeEach(func() {
+			By("creating an annotated Deployment")
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      deploymentName,
+					Namespace: namespace,
+					Annotations: map[string]string{
+						v1.EnabledAnnotation:        "true",
+						v1.MaxSurgeAnnotation:       "2",
+						v1.EvictionWindowAnnotation: "10m",
+					},
+				},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: int32Ptr(2),
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"app": "annotated"},
+					},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{
+							Labels: map[string]string{"app": "annotated"},
+						},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "nginx", Image: "nginx:latest"}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+
+			By("creating a PDB selecting its pods")
+			pdb := &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "annotated-pdb",
+					Namespace: namespace,
+				},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					MinAvailable: &intstr.IntOrString{IntVal: 1},
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"app": "annotated"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pdb)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			By("cleaning up resources")
+			for _, obj := range []client.Object{
+				&v1.PDBWatcher{ObjectMeta: metav1.ObjectMeta{Name: watcherKey.Name, Namespace: namespace}},
+				&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: deploymentName, Namespace: namespace}},
+				&policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "annotated-pdb", Namespace: namespace}},
+				&policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "overlapping-pdb", Namespace: namespace}},
+			} {
+				Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, obj))).To(Succeed())
+				Eventually(func() bool {
+					return errors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), obj))
+				}, time.Second*10, time.Millisecond*250).Should(BeTrue())
+			}
+		})
+
+		It("should manage an implicit PDBWatcher from the annotations", func() {
+			controllerReconciler := &WorkloadReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Kind:   v1.DeploymentKind,
+			}
+
+			By("reconciling the opted-in Deployment")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: deploymentKey})
+			Expect(err).NotTo(HaveOccurred())
+
+			pdbWatcher := &v1.PDBWatcher{}
+			Expect(k8sClient.Get(ctx, watcherKey, pdbWatcher)).To(Succeed())
+			Expect(pdbWatcher.Spec.PDBName).To(Equal("annotated-pdb"))
+			Expect(pdbWatcher.Spec.DeploymentName).To(Equal(deploymentName))
+			Expect(pdbWatcher.Spec.TargetKind).To(Equal(v1.DeploymentKind))
+			Expect(pdbWatcher.Spec.MaxSurge).To(Equal(&intstr.IntOrString{IntVal: 2}))
+			Expect(pdbWatcher.Spec.EvictionWindow.Duration).To(Equal(10 * time.Minute))
+
+			By("removing the opt-in annotation")
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, deploymentKey, deployment)).To(Succeed())
+			delete(deployment.Annotations, v1.EnabledAnnotation)
+			Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: deploymentKey})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, watcherKey, pdbWatcher))).To(BeTrue())
+		})
+
+		It("should delete the implicit PDBWatcher once another PDB covers the Deployment", func() {
+			controllerReconciler := &WorkloadReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Kind:   v1.DeploymentKind,
+			}
+
+			By("reconciling the opted-in Deployment")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: deploymentKey})
+			Expect(err).NotTo(HaveOccurred())
+			pdbWatcher := &v1.PDBWatcher{}
+			Expect(k8sClient.Get(ctx, watcherKey, pdbWatcher)).To(Succeed())
+
+			By("creating a second PDB selecting its pods")
+			pdb := &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "overlapping-pdb",
+					Namespace: namespace,
+				},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					MaxUnavailable: &intstr.IntOrString{IntVal: 1},
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"app": "annotated"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pdb)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: deploymentKey})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, watcherKey, pdbWatcher))).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Outcomes of an intercepted eviction
+const (
+	OutcomeAllowed = "allowed" // Allowed and queued to be recorded
+	OutcomeDenied  = "denied"  // Denied while a SurgeFirst PDBWatcher surges
+	OutcomeDryRun  = "dry_run" // Dry-run, allowed without being recorded
+	OutcomeFailed  = "failed"  // Allowed, but could not be recorded
+
+	OutcomeOverlappingPDBs = "overlapping_pdbs" // Allowed but not recorded, the eviction API refuses pods covered by several PDBs
+	OutcomeUnwatched       = "unwatched"        // Allowed but not recorded, no PDBWatcher watches a PDB of the pod
+)
+
+var (
+	// EvictionsIntercepted counts the evictions the webhook admitted, per watched PDB of the pod
+	EvictionsIntercepted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pdb_autoscaler_evictions_intercepted_total",
+		Help: "Evictions intercepted by the webhook, by namespace, watched PDB and outcome",
+	}, []string{"namespace", "pdb", "outcome"})
+
+	// AdmissionDuration is the latency of the webhook's eviction admissions
+	AdmissionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pdb_autoscaler_webhook_admission_duration_seconds",
+		Help:    "Time taken by the webhook to admit an eviction, by outcome",
+		Buckets: []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	}, []string{"outcome"})
+
+	// EvictionQueueDepth is the number of evictions waiting to be recorded
+	EvictionQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "pdb_autoscaler_webhook_eviction_queue_depth",
+		Help: "Evictions waiting to be recorded by the webhook",
+	})
+
+	// EvictionQueueDropped counts the evictions dropped because the queue was full
+	EvictionQueueDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "pdb_autoscaler_webhook_eviction_queue_dropped_total",
+		Help: "Evictions dropped because the webhook's recording queue was full",
+	})
+
+	// SurgesStarted counts the scale ups of a workload above its baseline
+	SurgesStarted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pdb_autoscaler_surges_started_total",
+		Help: "Surges started because evictions were blocked by a PDB, by namespace and PDBWatcher",
+	}, []string{"namespace", "pdbwatcher"})
+
+	// SurgesCompleted counts the surges reverted once the PDB allowed disruptions again
+	SurgesCompleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pdb_autoscaler_surges_completed_total",
+		Help: "Surges reverted to the baseline once the PDB allowed disruptions, by namespace and PDBWatcher",
+	}, []string{"namespace", "pdbwatcher"})
+
+	// SurgesRolledBack counts the surges abandoned because the workload was scaled by someone else
+	SurgesRolledBack = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pdb_autoscaler_surges_rolled_back_total",
+		Help: "Surges abandoned because the workload was changed by someone else, by namespace and PDBWatcher",
+	}, []string{"namespace", "pdbwatcher"})
+
+	// SurgedReplicas is the number of replicas currently added above each PDBWatcher's baseline
+	SurgedReplicas = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pdb_autoscaler_surged_replicas",
+		Help: "Replicas currently added above the baseline, by namespace and PDBWatcher",
+	}, []string{"namespace", "pdbwatcher"})
+
+	// SurgeDuration is the time from the start of a surge to its end
+	SurgeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pdb_autoscaler_surge_duration_seconds",
+		Help:    "Time from the start of a surge until it was completed or rolled back, by namespace",
+		Buckets: prometheus.ExponentialBuckets(15, 2, 10), // 15s to ~2h
+	}, []string{"namespace"})
+
+	// OverlappingPDBPods is the number of pods of each PDBWatcher's workload that are covered by more than one PDB
+	OverlappingPDBPods = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pdb_autoscaler_overlapping_pdb_pods",
+		Help: "Pods of the watched workload covered by more than one PDB, which the eviction API refuses to evict, by namespace and PDBWatcher",
+	}, []string{"namespace", "pdbwatcher"})
+
+	// ReconcileErrors counts the reconciles that failed, by the step that failed
+	ReconcileErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "pdb_autoscaler_reconcile_errors_total",
+		Help: "Reconcile errors, by controller and reason",
+	}, []string{"controller", "reason"})
+)
+
+// DeletePDBWatcher removes the series of a deleted PDBWatcher, so they don't outlive it
+func DeletePDBWatcher(namespace, name string) {
+	for _, vec := range []*prometheus.MetricVec{
+		SurgesStarted.MetricVec,
+		SurgesCompleted.MetricVec,
+		SurgesRolledBack.MetricVec,
+		SurgedReplicas.MetricVec,
+		OverlappingPDBPods.MetricVec,
+	} {
+		vec.DeleteLabelValues(namespace, name)
+	}
+}
+
+func init() {
+	metrics.Registry.MustRegister(
+		EvictionsIntercepted,
+		AdmissionDuration,
+		EvictionQueueDepth,
+		EvictionQueueDropped,
+		SurgesStarted,
+		SurgesCompleted,
+		SurgesRolledBack,
+		SurgedReplicas,
+		SurgeDuration,
+		OverlappingPDBPods,
+		ReconcileErrors,
+	)
+}
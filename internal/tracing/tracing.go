@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// annotationPrefix namespaces the W3C trace context headers stored as annotations,
+// e.g. pdb-autoscaler/traceparent
+const annotationPrefix = "pdb-autoscaler/"
+
+// Tracer returns the tracer of the controller and webhook. It is a no-op until Setup
+// installs an exporting tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer("github.com/Javier090/k8s-pdb-autoscaler")
+}
+
+// Setup exports spans over OTLP/gRPC to endpoint, e.g. a local collector at
+// localhost:4317. The returned function flushes and stops the exporter.
+func Setup(ctx context.Context, serviceName, endpoint string, insecure bool) (func(context.Context) error, error) {
+	options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if insecure {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// annotationCarrier carries a trace context in an object's annotations
+type annotationCarrier map[string]string
+
+func (c annotationCarrier) Get(key string) string {
+	return c[annotationPrefix+key]
+}
+
+func (c annotationCarrier) Set(key, value string) {
+	c[annotationPrefix+key] = value
+}
+
+func (c annotationCarrier) Keys() []string {
+	var keys []string
+	for key := range c {
+		if strings.HasPrefix(key, annotationPrefix) {
+			keys = append(keys, strings.TrimPrefix(key, annotationPrefix))
+		}
+	}
+	return keys
+}
+
+// InjectAnnotations stores the trace context of ctx in annotations, so the object's
+// consumers can continue the trace. It returns annotations, allocated if nil.
+func InjectAnnotations(ctx context.Context, annotations map[string]string) map[string]string {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return annotations
+	}
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	propagation.TraceContext{}.Inject(ctx, annotationCarrier(annotations))
+	return annotations
+}
+
+// ExtractAnnotations returns ctx carrying the trace context stored in annotations, if any
+func ExtractAnnotations(ctx context.Context, annotations map[string]string) context.Context {
+	return propagation.TraceContext{}.Extract(ctx, annotationCarrier(annotations))
+}
@@ -0,0 +1,33 @@
+package tracing
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ = Describe("Annotations", func() {
+	It("should carry a trace context through an object's annotations", func() {
+		spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{0x01, 0x02, 0x03},
+			SpanID:     trace.SpanID{0x04, 0x05},
+			TraceFlags: trace.FlagsSampled,
+		})
+		ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
+
+		annotations := InjectAnnotations(ctx, nil)
+		Expect(annotations).To(HaveKey("pdb-autoscaler/traceparent"))
+
+		extracted := trace.SpanContextFromContext(ExtractAnnotations(context.Background(), annotations))
+		Expect(extracted.TraceID()).To(Equal(spanContext.TraceID()))
+		Expect(extracted.SpanID()).To(Equal(spanContext.SpanID()))
+		Expect(extracted.IsRemote()).To(BeTrue())
+	})
+
+	It("should leave annotations alone without a trace", func() {
+		Expect(InjectAnnotations(context.Background(), nil)).To(BeNil())
+		Expect(trace.SpanContextFromContext(ExtractAnnotations(context.Background(), nil)).IsValid()).To(BeFalse())
+	})
+})
@@ -0,0 +1,280 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+	"github.com/Javier090/k8s-pdb-autoscaler/internal/metrics"
+	"github.com/Javier090/k8s-pdb-autoscaler/internal/tracing"
+)
+
+// EvictionPath is the default path the eviction webhook is served on
+const EvictionPath = "/validate-eviction"
+
+// surgeRetryAfter is the Retry-After hint of evictions denied while a surge is in progress
+const surgeRetryAfter = 10 * time.Second
+
+// surgeHoldTimeout bounds how long evictions are denied for a surge, e.g. when the surged
+// pods never become Ready. After that the PDB answers them again.
+const surgeHoldTimeout = 5 * time.Minute
+
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps.mydomain.com,resources=pdbwatchers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=apps.mydomain.com,resources=pdbwatchers/status,verbs=patch
+// +kubebuilder:rbac:groups=apps.mydomain.com,resources=evictionrecords,verbs=create;patch
+
+// SetupEvictionWebhookWithManager registers the eviction webhook on the manager's
+// webhook server at path, along with the index and recorder it feeds
+func SetupEvictionWebhookWithManager(ctx context.Context, mgr ctrl.Manager, path string) error {
+	// Index PDBWatchers by their PDB's selector, fed by the manager's informers
+	index, err := NewWatcherIndex(ctx, mgr.GetCache())
+	if err != nil {
+		return fmt.Errorf("unable to create PDBWatcher index: %w", err)
+	}
+	if err := mgr.AddReadyzCheck("eviction-index", index.Checker); err != nil {
+		return fmt.Errorf("unable to add PDBWatcher index ready check: %w", err)
+	}
+
+	// Record evictions in batches, off the admission path
+	recorder := &EvictionRecorder{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		Index:  index,
+	}
+	if err := mgr.Add(recorder); err != nil {
+		return fmt.Errorf("unable to add eviction recorder to manager: %w", err)
+	}
+
+	mgr.GetWebhookServer().Register(path, &admission.Webhook{
+		Handler: &EvictionHandler{
+			Client:   mgr.GetClient(),
+			Index:    index,
+			Recorder: recorder,
+			decoder:  admission.NewDecoder(mgr.GetScheme()),
+		},
+	})
+	return nil
+}
+
+// EvictionHandler intercepts pod evictions and queues them to be recorded
+type EvictionHandler struct {
+	Client   client.Client
+	Index    *WatcherIndex
+	Recorder *EvictionRecorder
+	decoder  admission.Decoder
+}
+
+func (e *EvictionHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := log.FromContext(ctx)
+
+	// Only pods/eviction is handled, anything else is a misconfigured webhook and is let through
+	if req.SubResource != "eviction" {
+		logger.Info(fmt.Sprintf("Ignoring %s request for %s/%s subresource %q", req.Operation, req.Resource.Resource, req.Name, req.SubResource))
+		return admission.Allowed("not an eviction")
+	}
+
+	// The span's context is recorded with the eviction, so the surge it causes joins the trace
+	ctx, span := tracing.Tracer().Start(ctx, "eviction.admit", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", req.Namespace),
+		attribute.String("k8s.pod.name", req.Name),
+		attribute.String("user.name", req.UserInfo.Username),
+	))
+	start := time.Now()
+	outcome := metrics.OutcomeFailed
+	var matches []watcherMatch
+	defer func() {
+		metrics.AdmissionDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+		observeEviction(req.Namespace, matches, outcome)
+		span.SetAttributes(attribute.String("outcome", outcome))
+		span.End()
+	}()
+
+	podKey, evictionDryRun, err := e.decodeEviction(req)
+	if err != nil {
+		logger.Error(err, "Failed to decode Eviction")
+		return recordingFailed(err)
+	}
+	logger.Info(fmt.Sprintf("Received eviction request for pod %s", podKey))
+
+	// Dry-run evictions (e.g. kubectl drain --dry-run=server) are never recorded, the webhook
+	// declares sideEffects: NoneOnDryRun
+	dryRun := req.DryRun != nil && *req.DryRun || evictionDryRun
+
+	// Fetch the pod to get its labels
+	pod := &corev1.Pod{}
+	err = e.Client.Get(ctx, podKey, pod)
+	if err != nil {
+		logger.Error(err, "Failed to fetch Pod")
+		return recordingFailed(err)
+	}
+
+	// The eviction API refuses pods covered by several PDBs, recording them would cause a surge that can't help
+	matches = e.Index.Lookup(pod.Namespace, pod.Labels)
+	if covering := e.Index.CoveringPDBs(pod.Namespace, pod.Labels); len(covering) > 1 {
+		logger.Info(fmt.Sprintf("Not recording eviction of %s, it is covered by multiple PDBs %v", podKey, covering))
+		outcome = metrics.OutcomeOverlappingPDBs
+		return admission.Allowed("pod is covered by multiple PDBs")
+	}
+
+	// The PDB answers dry-runs, they never cause a surge
+	if dryRun {
+		logger.Info(fmt.Sprintf("Not recording dry-run eviction of %s", podKey))
+		outcome = metrics.OutcomeDryRun
+		return admission.Allowed("dry-run eviction allowed")
+	}
+
+	// Pods no PDBWatcher watches are never recorded, during a mass drain they would crowd watched ones out of the queue
+	if len(matches) == 0 {
+		outcome = metrics.OutcomeUnwatched
+		return admission.Allowed("pod is not watched")
+	}
+
+	// Queue the eviction, it is attributed to a PDBWatcher and recorded off the admission path
+	evictionLog := myappsv1.EvictionLog{
+		PodName:      pod.Name,
+		PodUID:       pod.UID,
+		NodeName:     pod.Spec.NodeName,
+		Requester:    req.UserInfo.Username,
+		EvictionTime: metav1.Now(),
+	}
+	recorded := e.Recorder.Enqueue(ctx, pod.Namespace, pod.Labels, admittedDisruptions(matches), evictionLog)
+	if !recorded {
+		logger.Info(fmt.Sprintf("Eviction queue full, dropping eviction of %s", podKey))
+	}
+
+	// SurgeFirst PDBWatchers hold evictions until their surge makes room under the PDB
+	if response, denied := surgeInProgress(matches, time.Now()); denied {
+		logger.Info(fmt.Sprintf("Denying eviction of %s: %s", podKey, response.Result.Message))
+		outcome = metrics.OutcomeDenied
+		return response
+	}
+
+	if recorded {
+		outcome = metrics.OutcomeAllowed
+	}
+	return admission.Allowed("eviction allowed")
+}
+
+// observeEviction counts an intercepted eviction once for each watched PDB of the pod
+func observeEviction(namespace string, matches []watcherMatch, outcome string) {
+	if len(matches) == 0 {
+		metrics.EvictionsIntercepted.WithLabelValues(namespace, "", outcome).Inc()
+		return
+	}
+	for _, match := range matches {
+		metrics.EvictionsIntercepted.WithLabelValues(namespace, match.PDB.Name, outcome).Inc()
+	}
+}
+
+// admittedDisruptions returns the DisruptionsAllowed of each matched PDB, by PDB name
+func admittedDisruptions(matches []watcherMatch) map[string]int32 {
+	disruptionsAllowed := make(map[string]int32, len(matches))
+	for _, match := range matches {
+		disruptionsAllowed[match.PDB.Name] = match.PDB.Status.DisruptionsAllowed
+	}
+	return disruptionsAllowed
+}
+
+// decodeEviction decodes the policy/v1 or policy/v1beta1 Eviction in the request, returning
+// the pod it evicts and whether its DeleteOptions ask for a dry-run
+func (e *EvictionHandler) decodeEviction(req admission.Request) (types.NamespacedName, bool, error) {
+	var meta metav1.ObjectMeta
+	var deleteOptions *metav1.DeleteOptions
+	switch req.Kind {
+	case metav1.GroupVersionKind{Group: policyv1.GroupName, Version: "v1", Kind: "Eviction"}:
+		eviction := &policyv1.Eviction{}
+		if err := e.decoder.Decode(req, eviction); err != nil {
+			return types.NamespacedName{}, false, err
+		}
+		meta, deleteOptions = eviction.ObjectMeta, eviction.DeleteOptions
+	case metav1.GroupVersionKind{Group: policyv1beta1.GroupName, Version: "v1beta1", Kind: "Eviction"}:
+		eviction := &policyv1beta1.Eviction{}
+		if err := e.decoder.Decode(req, eviction); err != nil {
+			return types.NamespacedName{}, false, err
+		}
+		meta, deleteOptions = eviction.ObjectMeta, eviction.DeleteOptions
+	default:
+		return types.NamespacedName{}, false, fmt.Errorf("unsupported eviction kind %s", req.Kind)
+	}
+
+	// The API server requires the Eviction's name to match the pod's, fall back to the request's
+	podKey := types.NamespacedName{Namespace: meta.Namespace, Name: meta.Name}
+	if podKey.Namespace == "" {
+		podKey.Namespace = req.Namespace
+	}
+	if podKey.Name == "" {
+		podKey.Name = req.Name
+	}
+	return podKey, deleteOptions != nil && len(deleteOptions.DryRun) > 0, nil
+}
+
+// surgeInProgress denies the eviction if a SurgeFirst PDBWatcher is surging and its PDB
+// allows no disruptions, for at most surgeHoldTimeout since the surge started. The 429 and
+// Retry-After mirror the PDB's own rejection, so clients such as kubectl drain retry, and
+// are let through once the surged pods are Ready.
+func surgeInProgress(matches []watcherMatch, now time.Time) (admission.Response, bool) {
+	for _, match := range matches {
+		pdbWatcher, pdb := match.PDBWatcher, match.PDB
+		if pdbWatcher.Spec.EvictionMode != myappsv1.EvictionModeSurgeFirst || pdb.Status.DisruptionsAllowed > 0 {
+			continue
+		}
+		if pdbWatcher.Status.SurgeStartTime == nil && pdbWatcher.Status.DesiredSurge == 0 {
+			continue // Not surging, e.g. too few evictions for the trigger or cooling down, the PDB answers
+		}
+		if start := pdbWatcher.Status.SurgeStartTime; start != nil && !now.Before(start.Add(surgeHoldTimeout)) {
+			continue // The surge isn't making room, the PDB answers
+		}
+		if meta.IsStatusConditionFalse(pdbWatcher.Status.Conditions, myappsv1.ConditionSurgeEnabled) ||
+			meta.IsStatusConditionTrue(pdbWatcher.Status.Conditions, myappsv1.ConditionDegraded) {
+			continue // No surge is coming, the PDB blocks the eviction by design or because pods are failing
+		}
+
+		kind := pdbWatcher.Spec.TargetKind
+		if kind == "" {
+			kind = myappsv1.DeploymentKind
+		}
+		message := fmt.Sprintf("PDB %s allows no disruptions, PDBWatcher %s is surging %s %s, retry once the new pods are Ready",
+			pdb.Name, pdbWatcher.Name, kind, pdbWatcher.Spec.DeploymentName)
+		return admission.Response{AdmissionResponse: admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    http.StatusTooManyRequests,
+				Reason:  metav1.StatusReasonTooManyRequests,
+				Message: message,
+				Details: &metav1.StatusDetails{
+					RetryAfterSeconds: int32(surgeRetryAfter.Seconds()),
+					Causes: []metav1.StatusCause{{
+						Type:    policyv1.DisruptionBudgetCause,
+						Message: fmt.Sprintf("The disruption budget %s needs more healthy pods, PDBWatcher %s is surging them", pdb.Name, pdbWatcher.Name),
+					}},
+				},
+			},
+		}}, true
+	}
+	return admission.Response{}, false
+}
+
+// recordingFailed allows an eviction the webhook could not record. Failing the
+// admission instead would block the eviction, as the webhook uses failurePolicy: Fail.
+func recordingFailed(err error) admission.Response {
+	return admission.Allowed(fmt.Sprintf("eviction allowed, unable to record it: %v", err))
+}
@@ -0,0 +1,222 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+	"github.com/Javier090/k8s-pdb-autoscaler/internal/metrics"
+)
+
+var _ = Describe("Eviction handler", func() {
+	const namespace = "default"
+
+	var (
+		ctx     context.Context
+		handler *EvictionHandler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "example-pod", Namespace: namespace, UID: "uid-1", Labels: map[string]string{"app": "example"}}}
+		handler = &EvictionHandler{
+			Client: fake.NewClientBuilder().WithObjects(pod).Build(),
+			Index: &WatcherIndex{
+				pdbs:     make(map[types.NamespacedName]indexedPDB),
+				watchers: make(map[string]map[string]map[string]*myappsv1.PDBWatcher),
+			},
+			Recorder: &EvictionRecorder{},
+			decoder:  admission.NewDecoder(clientgoscheme.Scheme),
+		}
+		handler.Index.setPDB(&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "example-pdb", Namespace: namespace},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "example"}}},
+		})
+		handler.Index.setWatcher(nil, &myappsv1.PDBWatcher{
+			ObjectMeta: metav1.ObjectMeta{Name: "example-pdbwatcher", Namespace: namespace},
+			Spec:       myappsv1.PDBWatcherSpec{PDBName: "example-pdb", DeploymentName: "example-deployment"},
+		})
+	})
+
+	// evictionRequest builds the admission request for an Eviction of the pod
+	evictionRequest := func(eviction runtime.Object, kind metav1.GroupVersionKind) admission.Request {
+		raw, err := json.Marshal(eviction)
+		Expect(err).NotTo(HaveOccurred())
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Kind:        kind,
+			Resource:    metav1.GroupVersionResource{Version: "v1", Resource: "pods"},
+			SubResource: "eviction",
+			Name:        "example-pod",
+			Namespace:   namespace,
+			Operation:   admissionv1.Create,
+			Object:      runtime.RawExtension{Raw: raw},
+		}}
+	}
+
+	It("should record policy/v1 Evictions", func() {
+		eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: "example-pod", Namespace: namespace}}
+		response := handler.Handle(ctx, evictionRequest(eviction, metav1.GroupVersionKind{Group: "policy", Version: "v1", Kind: "Eviction"}))
+		Expect(response.Allowed).To(BeTrue())
+		Expect(handler.Recorder.pending).To(HaveKey(factKey{podUID: "uid-1"}))
+	})
+
+	It("should not record evictions of pods no PDBWatcher watches", func() {
+		handler.Index.deleteWatcher(&myappsv1.PDBWatcher{
+			ObjectMeta: metav1.ObjectMeta{Name: "example-pdbwatcher", Namespace: namespace},
+			Spec:       myappsv1.PDBWatcherSpec{PDBName: "example-pdb"},
+		})
+		unwatched := testutil.ToFloat64(metrics.EvictionsIntercepted.WithLabelValues(namespace, "", metrics.OutcomeUnwatched))
+		eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: "example-pod", Namespace: namespace}}
+		response := handler.Handle(ctx, evictionRequest(eviction, metav1.GroupVersionKind{Group: "policy", Version: "v1", Kind: "Eviction"}))
+		Expect(response.Allowed).To(BeTrue())
+		Expect(handler.Recorder.pending).To(BeEmpty())
+		Expect(testutil.ToFloat64(metrics.EvictionsIntercepted.WithLabelValues(namespace, "", metrics.OutcomeUnwatched))).To(Equal(unwatched + 1))
+	})
+
+	It("should count dry-run evictions without recording them", func() {
+		dryRuns := testutil.ToFloat64(metrics.EvictionsIntercepted.WithLabelValues(namespace, "example-pdb", metrics.OutcomeDryRun))
+		eviction := &policyv1.Eviction{
+			ObjectMeta:    metav1.ObjectMeta{Name: "example-pod", Namespace: namespace},
+			DeleteOptions: &metav1.DeleteOptions{DryRun: []string{metav1.DryRunAll}},
+		}
+		response := handler.Handle(ctx, evictionRequest(eviction, metav1.GroupVersionKind{Group: "policy", Version: "v1", Kind: "Eviction"}))
+		Expect(response.Allowed).To(BeTrue())
+		Expect(handler.Recorder.pending).To(BeEmpty())
+		Expect(testutil.ToFloat64(metrics.EvictionsIntercepted.WithLabelValues(namespace, "example-pdb", metrics.OutcomeDryRun))).To(Equal(dryRuns + 1))
+
+		By("honouring the admission request's dryRun too")
+		dryRun := true
+		req := evictionRequest(&policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: "example-pod", Namespace: namespace}},
+			metav1.GroupVersionKind{Group: "policy", Version: "v1", Kind: "Eviction"})
+		req.DryRun = &dryRun
+		Expect(handler.Handle(ctx, req).Allowed).To(BeTrue())
+		Expect(handler.Recorder.pending).To(BeEmpty())
+	})
+
+	It("should record policy/v1beta1 Evictions", func() {
+		eviction := &policyv1beta1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: "example-pod", Namespace: namespace}}
+		response := handler.Handle(ctx, evictionRequest(eviction, metav1.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "Eviction"}))
+		Expect(response.Allowed).To(BeTrue())
+		Expect(handler.Recorder.pending).To(HaveKey(factKey{podUID: "uid-1"}))
+	})
+
+	It("should not record evictions of pods covered by multiple PDBs", func() {
+		for _, name := range []string{"example-pdb", "other-pdb"} {
+			handler.Index.setPDB(&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{}},
+			})
+		}
+		eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: "example-pod", Namespace: namespace}}
+		response := handler.Handle(ctx, evictionRequest(eviction, metav1.GroupVersionKind{Group: "policy", Version: "v1", Kind: "Eviction"}))
+		Expect(response.Allowed).To(BeTrue())
+		Expect(handler.Recorder.pending).To(BeEmpty())
+		Expect(handler.Index.CoveringPDBs(namespace, nil)).To(Equal([]string{"example-pdb", "other-pdb"}))
+	})
+
+	It("should let requests other than evictions through without recording them", func() {
+		req := evictionRequest(&policyv1.Eviction{}, metav1.GroupVersionKind{Group: "policy", Version: "v1", Kind: "Eviction"})
+		req.SubResource = "status"
+		Expect(handler.Handle(ctx, req).Allowed).To(BeTrue())
+		Expect(handler.Recorder.pending).To(BeEmpty())
+	})
+
+	Context("When a PDBWatcher surges first", func() {
+		match := func(mode myappsv1.EvictionMode, disruptionsAllowed int32) watcherMatch {
+			return watcherMatch{
+				PDBWatcher: &myappsv1.PDBWatcher{
+					ObjectMeta: metav1.ObjectMeta{Name: "example-pdbwatcher"},
+					Spec: myappsv1.PDBWatcherSpec{
+						PDBName:        "example-pdb",
+						DeploymentName: "example-deployment",
+						EvictionMode:   mode,
+					},
+				},
+				PDB: &policyv1.PodDisruptionBudget{
+					ObjectMeta: metav1.ObjectMeta{Name: "example-pdb"},
+					Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: disruptionsAllowed},
+				},
+			}
+		}
+		// surging returns the match of a SurgeFirst PDBWatcher that started surging at start
+		surging := func(disruptionsAllowed int32, start time.Time) watcherMatch {
+			surging := match(myappsv1.EvictionModeSurgeFirst, disruptionsAllowed)
+			surging.PDBWatcher.Status.SurgeStartTime = &metav1.Time{Time: start}
+			return surging
+		}
+		now := time.Now()
+
+		It("should deny evictions with a retry hint while the PDB allows no disruptions", func() {
+			response, denied := surgeInProgress([]watcherMatch{surging(0, now)}, now)
+			Expect(denied).To(BeTrue())
+			Expect(response.Allowed).To(BeFalse())
+			Expect(response.Result.Code).To(Equal(int32(http.StatusTooManyRequests)))
+			Expect(response.Result.Details.RetryAfterSeconds).To(BeNumerically(">", 0))
+			Expect(response.Result.Message).To(ContainSubstring("Deployment example-deployment"))
+		})
+
+		It("should let evictions through while no surge is in progress", func() {
+			_, denied := surgeInProgress([]watcherMatch{match(myappsv1.EvictionModeSurgeFirst, 0)}, now)
+			Expect(denied).To(BeFalse())
+
+			By("denying them while a surge is requested from the baseline owner")
+			requesting := match(myappsv1.EvictionModeSurgeFirst, 0)
+			requesting.PDBWatcher.Status.DesiredSurge = 1
+			_, denied = surgeInProgress([]watcherMatch{requesting}, now)
+			Expect(denied).To(BeTrue())
+		})
+
+		It("should let evictions through once the surge was held too long", func() {
+			_, denied := surgeInProgress([]watcherMatch{surging(0, now.Add(-surgeHoldTimeout))}, now)
+			Expect(denied).To(BeFalse())
+		})
+
+		It("should let evictions through once the surge is Ready", func() {
+			_, denied := surgeInProgress([]watcherMatch{surging(1, now)}, now)
+			Expect(denied).To(BeFalse())
+		})
+
+		It("should never deny evictions for PDBWatchers in Allow mode", func() {
+			_, denied := surgeInProgress([]watcherMatch{match("", 0), match(myappsv1.EvictionModeAllow, 0)}, now)
+			Expect(denied).To(BeFalse())
+		})
+
+		It("should let evictions through when the PDBWatcher refuses to surge", func() {
+			refusing := surging(0, now)
+			refusing.PDBWatcher.Status.Conditions = []metav1.Condition{{
+				Type:   myappsv1.ConditionSurgeEnabled,
+				Status: metav1.ConditionFalse,
+				Reason: myappsv1.SurgeDisabledMaxUnavailable,
+			}}
+			_, denied := surgeInProgress([]watcherMatch{refusing}, now)
+			Expect(denied).To(BeFalse())
+		})
+
+		It("should let evictions through while pods of the workload are failing", func() {
+			degraded := surging(0, now)
+			degraded.PDBWatcher.Status.Conditions = []metav1.Condition{{
+				Type:   myappsv1.ConditionDegraded,
+				Status: metav1.ConditionTrue,
+				Reason: myappsv1.DegradedPodsFailing,
+			}}
+			_, denied := surgeInProgress([]watcherMatch{degraded}, now)
+			Expect(denied).To(BeFalse())
+		})
+	})
+})
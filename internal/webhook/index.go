@@ -0,0 +1,211 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+
+	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// watcherMatch is a PDBWatcher whose PDB selects a pod
+type watcherMatch struct {
+	PDBWatcher *myappsv1.PDBWatcher
+	PDB        *policyv1.PodDisruptionBudget
+}
+
+// indexedPDB is a PDB with its parsed selector
+type indexedPDB struct {
+	pdb      *policyv1.PodDisruptionBudget
+	selector labels.Selector
+}
+
+// WatcherIndex maps PDB selectors to the PDBWatchers watching them. It is
+// maintained from the PDB and PDBWatcher informers, so finding the watchers
+// of an evicted pod is an in-memory match instead of API calls.
+type WatcherIndex struct {
+	mu       sync.RWMutex
+	pdbs     map[types.NamespacedName]indexedPDB
+	watchers map[string]map[string]map[string]*myappsv1.PDBWatcher // Namespace, then PDB name, then PDBWatcher name
+	synced   []toolscache.InformerSynced
+}
+
+// NewWatcherIndex creates a WatcherIndex fed by the cache's PDB and PDBWatcher informers
+func NewWatcherIndex(ctx context.Context, c cache.Cache) (*WatcherIndex, error) {
+	index := &WatcherIndex{
+		pdbs:     make(map[types.NamespacedName]indexedPDB),
+		watchers: make(map[string]map[string]map[string]*myappsv1.PDBWatcher),
+	}
+
+	pdbInformer, err := c.GetInformer(ctx, &policyv1.PodDisruptionBudget{})
+	if err != nil {
+		return nil, err
+	}
+	registration, err := pdbInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { index.setPDB(obj) },
+		UpdateFunc: func(_, obj interface{}) { index.setPDB(obj) },
+		DeleteFunc: func(obj interface{}) { index.deletePDB(obj) },
+	})
+	if err != nil {
+		return nil, err
+	}
+	index.synced = append(index.synced, registration.HasSynced)
+
+	watcherInformer, err := c.GetInformer(ctx, &myappsv1.PDBWatcher{})
+	if err != nil {
+		return nil, err
+	}
+	registration, err = watcherInformer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { index.setWatcher(nil, obj) },
+		UpdateFunc: func(oldObj, obj interface{}) { index.setWatcher(oldObj, obj) },
+		DeleteFunc: func(obj interface{}) { index.deleteWatcher(obj) },
+	})
+	if err != nil {
+		return nil, err
+	}
+	index.synced = append(index.synced, registration.HasSynced)
+
+	return index, nil
+}
+
+// HasSynced reports whether the index has seen every PDB and PDBWatcher in the cache
+func (i *WatcherIndex) HasSynced() bool {
+	for _, synced := range i.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// Checker is a readiness check failing until the index has synced. Before that, a pod's
+// PDBWatchers may be missing and its eviction would be let through unrecorded.
+func (i *WatcherIndex) Checker(_ *http.Request) error {
+	if !i.HasSynced() {
+		return errors.New("PDBWatcher index has not synced")
+	}
+	return nil
+}
+
+// Lookup returns every PDBWatcher whose PDB selects a pod with the given labels.
+// More than one match means several PDBs cover the pod.
+func (i *WatcherIndex) Lookup(namespace string, podLabels map[string]string) []watcherMatch {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var matches []watcherMatch
+	for pdbName, watchers := range i.watchers[namespace] {
+		indexed, ok := i.pdbs[types.NamespacedName{Namespace: namespace, Name: pdbName}]
+		if !ok || !indexed.selector.Matches(labels.Set(podLabels)) {
+			continue
+		}
+		for _, pdbWatcher := range watchers {
+			matches = append(matches, watcherMatch{PDBWatcher: pdbWatcher, PDB: indexed.pdb})
+		}
+	}
+	return matches
+}
+
+// CoveringPDBs returns the names of every PDB selecting a pod with the given labels,
+// watched or not. The eviction API refuses to evict a pod covered by more than one.
+func (i *WatcherIndex) CoveringPDBs(namespace string, podLabels map[string]string) []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var names []string
+	for key, indexed := range i.pdbs {
+		if key.Namespace == namespace && indexed.selector.Matches(labels.Set(podLabels)) {
+			names = append(names, key.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (i *WatcherIndex) setPDB(obj interface{}) {
+	pdb, ok := obj.(*policyv1.PodDisruptionBudget)
+	if !ok {
+		return
+	}
+	key := types.NamespacedName{Namespace: pdb.Namespace, Name: pdb.Name}
+
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		log.Log.WithName("eviction-index").Error(err, "Invalid PDB selector", "pdb", key)
+		selector = labels.Nothing()
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.pdbs[key] = indexedPDB{pdb: pdb, selector: selector}
+}
+
+func (i *WatcherIndex) deletePDB(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pdb, ok := obj.(*policyv1.PodDisruptionBudget)
+	if !ok {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.pdbs, types.NamespacedName{Namespace: pdb.Namespace, Name: pdb.Name})
+}
+
+func (i *WatcherIndex) setWatcher(oldObj, obj interface{}) {
+	pdbWatcher, ok := obj.(*myappsv1.PDBWatcher)
+	if !ok {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if oldWatcher, ok := oldObj.(*myappsv1.PDBWatcher); ok {
+		i.removeWatcher(oldWatcher)
+	}
+	if i.watchers[pdbWatcher.Namespace] == nil {
+		i.watchers[pdbWatcher.Namespace] = make(map[string]map[string]*myappsv1.PDBWatcher)
+	}
+	pdbWatchers := i.watchers[pdbWatcher.Namespace]
+	if pdbWatchers[pdbWatcher.Spec.PDBName] == nil {
+		pdbWatchers[pdbWatcher.Spec.PDBName] = make(map[string]*myappsv1.PDBWatcher)
+	}
+	pdbWatchers[pdbWatcher.Spec.PDBName][pdbWatcher.Name] = pdbWatcher
+}
+
+func (i *WatcherIndex) deleteWatcher(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	pdbWatcher, ok := obj.(*myappsv1.PDBWatcher)
+	if !ok {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.removeWatcher(pdbWatcher)
+}
+
+// removeWatcher drops a PDBWatcher from the index, the caller must hold the lock
+func (i *WatcherIndex) removeWatcher(pdbWatcher *myappsv1.PDBWatcher) {
+	pdbWatchers := i.watchers[pdbWatcher.Namespace]
+	delete(pdbWatchers[pdbWatcher.Spec.PDBName], pdbWatcher.Name)
+	if len(pdbWatchers[pdbWatcher.Spec.PDBName]) == 0 {
+		delete(pdbWatchers, pdbWatcher.Spec.PDBName)
+	}
+	if len(pdbWatchers) == 0 {
+		delete(i.watchers, pdbWatcher.Namespace)
+	}
+}
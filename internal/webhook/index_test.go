@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+
+	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+var _ = Describe("WatcherIndex", func() {
+	const namespace = "default"
+
+	It("should match pods to the PDBWatchers of the PDBs selecting them", func() {
+		index := &WatcherIndex{
+			pdbs:     make(map[types.NamespacedName]indexedPDB),
+			watchers: make(map[string]map[string]map[string]*myappsv1.PDBWatcher),
+		}
+		index.setPDB(&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "example-pdb", Namespace: namespace},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "example"}},
+			},
+		})
+		pdbWatcher := &myappsv1.PDBWatcher{
+			ObjectMeta: metav1.ObjectMeta{Name: "example-pdbwatcher", Namespace: namespace},
+			Spec:       myappsv1.PDBWatcherSpec{PDBName: "example-pdb"},
+		}
+		index.setWatcher(nil, pdbWatcher)
+
+		matches := index.Lookup(namespace, map[string]string{"app": "example"})
+		Expect(matches).To(HaveLen(1))
+		Expect(matches[0].PDBWatcher.Name).To(Equal("example-pdbwatcher"))
+		Expect(index.Lookup(namespace, map[string]string{"app": "other"})).To(BeEmpty())
+		Expect(index.Lookup("other", map[string]string{"app": "example"})).To(BeEmpty())
+
+		By("deleting the PDBWatcher")
+		index.deleteWatcher(pdbWatcher)
+		Expect(index.Lookup(namespace, map[string]string{"app": "example"})).To(BeEmpty())
+	})
+
+	It("should not be ready until its informers have synced", func() {
+		synced := false
+		index := &WatcherIndex{synced: []toolscache.InformerSynced{func() bool { return true }, func() bool { return synced }}}
+		Expect(index.Checker(nil)).NotTo(Succeed())
+
+		synced = true
+		Expect(index.Checker(nil)).To(Succeed())
+	})
+})
@@ -0,0 +1,323 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+	"github.com/Javier090/k8s-pdb-autoscaler/internal/metrics"
+	"github.com/Javier090/k8s-pdb-autoscaler/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// factKey coalesces the attempts to evict one pod
+type factKey struct {
+	podUID types.UID
+}
+
+// recordKey identifies the EvictionRecord of a pod's evictions attributed to a PDBWatcher
+type recordKey struct {
+	pdbWatcher types.NamespacedName
+	podUID     types.UID
+}
+
+// recordedEviction is an EvictionRecord later attempts to evict its pod are coalesced
+// into, until its PDBWatcher's eviction window since the first attempt is over
+type recordedEviction struct {
+	record  *myappsv1.EvictionRecord // As last written
+	expires time.Time
+}
+
+// evictionFact is an intercepted eviction waiting to be recorded
+type evictionFact struct {
+	namespace          string
+	podLabels          map[string]string
+	disruptionsAllowed map[string]int32 // DisruptionsAllowed of the watched PDBs at the latest admission, by PDB name
+	log                myappsv1.EvictionLog
+	span               trace.SpanContext // Admission of the latest attempt
+}
+
+// EvictionRecorder records evictions off the admission path. Facts are queued in
+// memory, coalescing repeated attempts for the same pod, and flushed every
+// FlushInterval as EvictionRecords plus one status patch per PDBWatcher. Drains retry
+// blocked evictions every few seconds, so attempts to evict a pod within the eviction
+// window are coalesced into its existing EvictionRecord across flushes.
+type EvictionRecorder struct {
+	Client        client.Client
+	Scheme        *runtime.Scheme
+	Index         *WatcherIndex
+	FlushInterval time.Duration // 1s if unset
+	MaxPending    int           // Facts held before new ones are dropped, 10000 if unset
+
+	mu      sync.Mutex
+	pending map[factKey]*evictionFact
+
+	recorded map[recordKey]*recordedEviction // Only used by flush
+}
+
+// Enqueue queues an eviction to be recorded, returning false if the queue is full.
+// disruptionsAllowed holds the watched PDBs' DisruptionsAllowed when the eviction was
+// admitted, by PDB name. The record continues the trace of ctx.
+func (r *EvictionRecorder) Enqueue(ctx context.Context, namespace string, podLabels map[string]string, disruptionsAllowed map[string]int32, evictionLog myappsv1.EvictionLog) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pending == nil {
+		r.pending = make(map[factKey]*evictionFact)
+	}
+
+	// Coalesce retries of the same eviction, drains retry blocked evictions every few seconds
+	key := factKey{podUID: evictionLog.PodUID}
+	if fact, ok := r.pending[key]; ok {
+		fact.log.Attempts++
+		fact.log.EvictionTime = evictionLog.EvictionTime
+		fact.log.Requester = evictionLog.Requester
+		fact.disruptionsAllowed = disruptionsAllowed
+		fact.span = trace.SpanContextFromContext(ctx)
+		return true
+	}
+
+	maxPending := r.MaxPending
+	if maxPending == 0 {
+		maxPending = 10000
+	}
+	if len(r.pending) >= maxPending {
+		metrics.EvictionQueueDropped.Inc()
+		return false
+	}
+
+	evictionLog.Attempts = 1
+	r.pending[key] = &evictionFact{
+		namespace:          namespace,
+		podLabels:          podLabels,
+		disruptionsAllowed: disruptionsAllowed,
+		log:                evictionLog,
+		span:               trace.SpanContextFromContext(ctx),
+	}
+	metrics.EvictionQueueDepth.Set(float64(len(r.pending)))
+	return true
+}
+
+// Start flushes the queue every FlushInterval until the context is cancelled
+func (r *EvictionRecorder) Start(ctx context.Context) error {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithName("eviction-recorder"))
+
+	interval := r.FlushInterval
+	if interval == 0 {
+		interval = time.Second
+	}
+
+	// Facts can only be attributed once the index has seen every PDB and PDBWatcher
+	if !toolscache.WaitForCacheSync(ctx.Done(), r.Index.HasSynced) {
+		return nil
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			// Record what is left with a fresh context, the manager's is already cancelled
+			flushCtx, cancel := context.WithTimeout(log.IntoContext(context.Background(), log.FromContext(ctx)), 5*time.Second)
+			r.flush(flushCtx)
+			cancel()
+			return nil
+		case <-ticker.C:
+			r.flush(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection returns false, every webhook replica records the evictions it intercepted
+func (r *EvictionRecorder) NeedLeaderElection() bool {
+	return false
+}
+
+// flush records every queued fact, grouped by namespace
+func (r *EvictionRecorder) flush(ctx context.Context) {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = nil
+	metrics.EvictionQueueDepth.Set(0)
+	r.mu.Unlock()
+
+	byNamespace := make(map[string][]*evictionFact)
+	for _, fact := range pending {
+		byNamespace[fact.namespace] = append(byNamespace[fact.namespace], fact)
+	}
+	for namespace, facts := range byNamespace {
+		r.recordNamespace(ctx, namespace, facts)
+	}
+}
+
+// recordNamespace attributes a namespace's facts to PDBWatchers and records them
+func (r *EvictionRecorder) recordNamespace(ctx context.Context, namespace string, facts []*evictionFact) {
+	logger := log.FromContext(ctx)
+
+	// Forget the records whose eviction window is over, the next attempt starts a new one
+	now := time.Now()
+	for key, recorded := range r.recorded {
+		if !now.Before(recorded.expires) {
+			delete(r.recorded, key)
+		}
+	}
+
+	// Record each eviction against every PDBWatcher whose PDB selects the pod
+	lastEvictions := make(map[*myappsv1.PDBWatcher]metav1.Time)
+	for _, fact := range facts {
+		matches := r.Index.Lookup(namespace, fact.podLabels)
+		if len(matches) > 1 {
+			logger.Info(fmt.Sprintf("Pod %s/%s is covered by %d watched PDBs, the eviction API refuses to evict it",
+				namespace, fact.log.PodName, len(matches)))
+		}
+
+		for _, match := range matches {
+			// The PDB's status has often changed since, after the eviction itself or a surge
+			evictionLog := fact.log
+			disruptionsAllowed, ok := fact.disruptionsAllowed[match.PDB.Name]
+			if !ok {
+				disruptionsAllowed = match.PDB.Status.DisruptionsAllowed // PDBWatcher created after the admission
+			}
+			evictionLog.DisruptionsAllowed = disruptionsAllowed
+			if err := r.record(trace.ContextWithRemoteSpanContext(ctx, fact.span), match.PDBWatcher, evictionLog); err != nil {
+				logger.Error(err, "Failed to record eviction", "pod", evictionLog.PodName)
+				continue
+			}
+
+			if evictionLog.EvictionTime.After(lastEvictions[match.PDBWatcher].Time) {
+				lastEvictions[match.PDBWatcher] = evictionLog.EvictionTime
+			}
+		}
+	}
+
+	// Patch the last eviction time into each PDBWatcher's status once per batch. A merge
+	// patch carries no resourceVersion, so concurrent writers can't conflict.
+	for pdbWatcher, lastEviction := range lastEvictions {
+		// Patch a copy, the index shares its objects with the informer cache
+		pdbWatcher = pdbWatcher.DeepCopy()
+		patch := client.MergeFrom(pdbWatcher.DeepCopy())
+		pdbWatcher.Status.LastEvictionTime = &lastEviction
+		err := r.Client.Status().Patch(ctx, pdbWatcher, patch)
+		if err != nil {
+			// The EvictionRecords are already created, the controller recomputes the status from them
+			logger.Error(err, "Failed to patch PDBWatcher status", "pdbWatcher", pdbWatcher.Name)
+		}
+	}
+}
+
+// record coalesces the eviction into the pod's EvictionRecord from earlier in the eviction
+// window, or creates a new one
+func (r *EvictionRecorder) record(ctx context.Context, pdbWatcher *myappsv1.PDBWatcher, evictionLog myappsv1.EvictionLog) error {
+	key := recordKey{pdbWatcher: client.ObjectKeyFromObject(pdbWatcher), podUID: evictionLog.PodUID}
+	if recorded, ok := r.recorded[key]; ok {
+		err := r.updateRecord(ctx, recorded.record, evictionLog)
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		delete(r.recorded, key) // Deleted since, e.g. by its TTL
+	}
+
+	evictionRecord, err := r.createRecord(ctx, pdbWatcher, evictionLog)
+	if err != nil {
+		return err
+	}
+	if r.recorded == nil {
+		r.recorded = make(map[recordKey]*recordedEviction)
+	}
+	window := myappsv1.DefaultEvictionWindow
+	if pdbWatcher.Spec.EvictionWindow != nil {
+		window = pdbWatcher.Spec.EvictionWindow.Duration
+	}
+	r.recorded[key] = &recordedEviction{record: evictionRecord, expires: time.Now().Add(window)}
+	return nil
+}
+
+// updateRecord adds the eviction's attempts to an EvictionRecord and moves it, and the trace
+// context it carries, to the latest attempt
+func (r *EvictionRecorder) updateRecord(ctx context.Context, evictionRecord *myappsv1.EvictionRecord, evictionLog myappsv1.EvictionLog) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "eviction.record", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", evictionRecord.Namespace),
+		attribute.String("k8s.pod.name", evictionLog.PodName),
+		attribute.String("pdbwatcher", evictionRecord.Spec.PDBWatcherName),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	// Patch a copy, so the next patch is still computed from what was last written if this one fails
+	updated := evictionRecord.DeepCopy()
+	updated.Annotations = tracing.InjectAnnotations(ctx, updated.Annotations) // The surge continues the latest attempt's trace
+	updated.Spec.Attempts = max(evictionRecord.Spec.Attempts, 1) + max(evictionLog.Attempts, 1)
+	updated.Spec.EvictionTime = evictionLog.EvictionTime
+	updated.Spec.NodeName = evictionLog.NodeName
+	updated.Spec.Requester = evictionLog.Requester
+	updated.Spec.DisruptionsAllowed = evictionLog.DisruptionsAllowed
+	err = r.Client.Patch(ctx, updated, client.MergeFrom(evictionRecord))
+	if err != nil {
+		return err
+	}
+	*evictionRecord = *updated
+
+	log.FromContext(ctx).Info(fmt.Sprintf("Recorded %d attempts to evict %s/%s as %s", updated.Spec.Attempts,
+		updated.Namespace, evictionLog.PodName, updated.Name), "requester", evictionLog.Requester)
+	return nil
+}
+
+// createRecord creates an EvictionRecord owned by the PDBWatcher, so it is garbage collected with it.
+// The record carries the trace context of ctx for the controller to continue.
+func (r *EvictionRecorder) createRecord(ctx context.Context, pdbWatcher *myappsv1.PDBWatcher, evictionLog myappsv1.EvictionLog) (_ *myappsv1.EvictionRecord, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "eviction.record", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", pdbWatcher.Namespace),
+		attribute.String("k8s.pod.name", evictionLog.PodName),
+		attribute.String("pdbwatcher", pdbWatcher.Name),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	evictionRecord := &myappsv1.EvictionRecord{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: evictionLog.PodName + "-",
+			Namespace:    pdbWatcher.Namespace,
+			Labels:       map[string]string{myappsv1.PDBWatcherLabel: pdbWatcher.Name},
+			Annotations:  tracing.InjectAnnotations(ctx, nil),
+		},
+		Spec: myappsv1.EvictionRecordSpec{
+			PDBWatcherName: pdbWatcher.Name,
+			EvictionLog:    evictionLog,
+		},
+	}
+	err = controllerutil.SetControllerReference(pdbWatcher, evictionRecord, r.Scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.Client.Create(ctx, evictionRecord)
+	if err != nil {
+		return nil, err
+	}
+
+	log.FromContext(ctx).Info(fmt.Sprintf("Recorded eviction of %s/%s as %s", pdbWatcher.Namespace, evictionLog.PodName, evictionRecord.Name),
+		"node", evictionLog.NodeName, "requester", evictionLog.Requester, "attempts", evictionLog.Attempts)
+	return evictionRecord, nil
+}
@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/trace"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	myappsv1 "github.com/Javier090/k8s-pdb-autoscaler/api/v1"
+)
+
+var _ = Describe("EvictionRecorder", func() {
+	const namespace = "default"
+
+	ctx := context.Background()
+
+	It("should coalesce repeated attempts to evict a pod", func() {
+		recorder := &EvictionRecorder{}
+		evictionLog := myappsv1.EvictionLog{PodName: "example-pod", PodUID: "uid-1", EvictionTime: metav1.Now()}
+		Expect(recorder.Enqueue(ctx, namespace, nil, nil, evictionLog)).To(BeTrue())
+		Expect(recorder.Enqueue(ctx, namespace, nil, nil, evictionLog)).To(BeTrue())
+
+		Expect(recorder.pending).To(HaveLen(1))
+		Expect(recorder.pending[factKey{podUID: "uid-1"}].log.Attempts).To(Equal(int32(2)))
+	})
+
+	It("should drop new evictions once the queue is full", func() {
+		recorder := &EvictionRecorder{MaxPending: 1}
+		Expect(recorder.Enqueue(ctx, namespace, nil, nil, myappsv1.EvictionLog{PodUID: "uid-1"})).To(BeTrue())
+		Expect(recorder.Enqueue(ctx, namespace, nil, nil, myappsv1.EvictionLog{PodUID: "uid-2"})).To(BeFalse())
+		Expect(recorder.Enqueue(ctx, namespace, nil, nil, myappsv1.EvictionLog{PodUID: "uid-1"})).To(BeTrue())
+	})
+
+	// flushingRecorder returns a recorder writing to a fake client, with a PDBWatcher
+	// watching a PDB that selects every pod and now allows 2 disruptions
+	flushingRecorder := func() *EvictionRecorder {
+		scheme := runtime.NewScheme()
+		Expect(myappsv1.AddToScheme(scheme)).To(Succeed())
+		pdbWatcher := &myappsv1.PDBWatcher{
+			ObjectMeta: metav1.ObjectMeta{Name: "example-pdbwatcher", Namespace: namespace, UID: "watcher-uid"},
+			Spec:       myappsv1.PDBWatcherSpec{PDBName: "example-pdb"},
+		}
+		recorder := &EvictionRecorder{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(pdbWatcher).WithStatusSubresource(pdbWatcher).Build(),
+			Scheme: scheme,
+			Index: &WatcherIndex{
+				pdbs:     make(map[types.NamespacedName]indexedPDB),
+				watchers: make(map[string]map[string]map[string]*myappsv1.PDBWatcher),
+			},
+		}
+		recorder.Index.setWatcher(nil, pdbWatcher)
+		recorder.Index.setPDB(&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "example-pdb", Namespace: namespace},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{}},
+			Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 2},
+		})
+		return recorder
+	}
+
+	It("should record the PDB's DisruptionsAllowed at admission", func() {
+		recorder := flushingRecorder() // Surged since the admission
+
+		evictionLog := myappsv1.EvictionLog{PodName: "example-pod", PodUID: "uid-1", EvictionTime: metav1.Now()}
+		Expect(recorder.Enqueue(ctx, namespace, nil, map[string]int32{"example-pdb": 0}, evictionLog)).To(BeTrue())
+		recorder.flush(ctx)
+
+		records := &myappsv1.EvictionRecordList{}
+		Expect(recorder.Client.List(ctx, records)).To(Succeed())
+		Expect(records.Items).To(HaveLen(1))
+		Expect(records.Items[0].Spec.EvictionLog.DisruptionsAllowed).To(BeZero())
+	})
+
+	It("should coalesce attempts to evict a pod across flushes within the eviction window", func() {
+		recorder := flushingRecorder()
+		evictionLog := myappsv1.EvictionLog{PodName: "example-pod", PodUID: "uid-1", EvictionTime: metav1.Now()}
+		for range 3 {
+			Expect(recorder.Enqueue(ctx, namespace, nil, nil, evictionLog)).To(BeTrue())
+			recorder.flush(ctx)
+		}
+
+		records := &myappsv1.EvictionRecordList{}
+		Expect(recorder.Client.List(ctx, records)).To(Succeed())
+		Expect(records.Items).To(HaveLen(1))
+		Expect(records.Items[0].Spec.Attempts).To(Equal(int32(3)))
+
+		By("continuing the trace of the latest attempt")
+		traced := trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{0x01, 0x02, 0x03},
+			SpanID:     trace.SpanID{0x04, 0x05},
+			TraceFlags: trace.FlagsSampled,
+		}))
+		Expect(recorder.Enqueue(traced, namespace, nil, nil, evictionLog)).To(BeTrue())
+		recorder.flush(ctx)
+		Expect(recorder.Client.List(ctx, records)).To(Succeed())
+		Expect(records.Items).To(HaveLen(1))
+		Expect(records.Items[0].Annotations["pdb-autoscaler/traceparent"]).To(ContainSubstring("01020300000000000000000000000000"))
+
+		By("starting a new record once the window is over")
+		for _, recorded := range recorder.recorded {
+			recorded.expires = time.Now()
+		}
+		Expect(recorder.Enqueue(ctx, namespace, nil, nil, evictionLog)).To(BeTrue())
+		recorder.flush(ctx)
+		Expect(recorder.Client.List(ctx, records)).To(Succeed())
+		Expect(records.Items).To(HaveLen(2))
+	})
+})